@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/woozymasta/bercon-cli/internal/acl"
+	"github.com/woozymasta/bercon-cli/internal/config"
+)
+
+// tokenEnv is the environment variable with the access token of the
+// current user; without it the OS user name is used.
+const tokenEnv = "BERCON_TOKEN"
+
+// loadACL returns the rc file ACL with denials logged to the [acl] log
+// file, or to stderr. The returned ACL is disabled when the rc file
+// defines no roles. close releases the log file and is never nil.
+func loadACL(opts *Options) (a *acl.ACL, closeLog func(), err error) {
+	closeLog = func() {}
+
+	f, ok, err := config.LoadRCFile(opts.Resources.RCPath)
+	if err != nil {
+		return nil, closeLog, fmt.Errorf("rc: %w", err)
+	}
+	if !ok {
+		return acl.New(config.ACLConfig{}), closeLog, nil
+	}
+
+	a = acl.New(f.ACL)
+	if !a.Enabled() {
+		return a, closeLog, nil
+	}
+
+	if f.ACL.Log == "" {
+		a.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+		return a, closeLog, nil
+	}
+
+	log, err := os.OpenFile(f.ACL.Log, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600) // #nosec G304 -- path from rc file
+	if err != nil {
+		return nil, closeLog, fmt.Errorf("acl log: %w", err)
+	}
+	a.SetLogger(slog.New(slog.NewJSONHandler(log, nil)))
+
+	return a, func() { _ = log.Close() }, nil
+}
+
+// guard checks commands of the current user or BERCON_TOKEN against the
+// ACL before they are sent.
+type guard struct {
+	acl *acl.ACL
+	id  acl.Identity
+}
+
+// newGuard identifies the caller when the ACL is enabled.
+func newGuard(a *acl.ACL) (*guard, error) {
+	g := &guard{acl: a}
+	if !a.Enabled() {
+		return g, nil
+	}
+
+	id, err := a.Identify(os.Getenv(tokenEnv))
+	if err != nil {
+		return nil, err
+	}
+	g.id = id
+
+	return g, nil
+}
+
+// check returns an error when cmd is not allowed on profile.
+func (g *guard) check(profile, cmd string) error {
+	return g.acl.Check(g.id, profile, cmd)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/internal/config"
+	"github.com/woozymasta/bercon-cli/internal/exporter"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+type ExporterOptions struct {
+	Listen   string   `long:"listen"   env:"LISTEN"   default:":9142" description:"Address to serve /metrics on"`
+	Profiles []string `long:"profile"  env:"PROFILES" env-delim:","   description:"Profile to export (repeatable, default: all rc profiles)"`
+	Interval int      `long:"interval" env:"INTERVAL" default:"15"    description:"Poll interval in seconds"`
+}
+
+// runExporter parses exporter options from args and serves metrics of the
+// rc profiles until interrupted.
+func runExporter(opts *Options, args []string) error {
+	var cmd struct {
+		Exporter ExporterOptions `group:"Exporter Settings" env-namespace:"BERCON_EXPORTER"`
+	}
+	if ok, err := parseSubcommand("exporter", &cmd, args); !ok {
+		return err
+	}
+	eo := cmd.Exporter
+
+	targets, err := profileTargets(opts, eo.Profiles)
+	if err != nil {
+		return err
+	}
+
+	rcACL, closeACL, err := loadACL(opts)
+	if err != nil {
+		return err
+	}
+	defer closeACL()
+
+	g, err := newGuard(rcACL)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		for _, cmd := range exporter.Commands {
+			if err := g.check(t.Name, cmd); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	exp := exporter.New(targets, time.Duration(eo.Interval)*time.Second)
+	exp.SetLogger(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exp)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exp.Run(ctx)
+	}()
+
+	logger.Info("exporter started", "listen", eo.Listen, "servers", len(targets))
+	err = listenAndServe(ctx, eo.Listen, mux)
+	stop()
+	<-done
+
+	return err
+}
+
+// profileTargets resolves the named rc profiles into exporter targets. When
+// names is empty, --profile of the main options or else all rc profiles
+// are used. Without rc profiles the connection
+// options of the command line make a single "default" target.
+func profileTargets(opts *Options, names []string) ([]exporter.Target, error) {
+	rcs, err := loadProfiles(opts, names)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]exporter.Target, 0, len(rcs))
+	for _, p := range rcs {
+		connOpts, err := profileConnOptions(opts, p.rc)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", p.name, err)
+		}
+
+		targets = append(targets, exporter.Target{
+			Name:     p.name,
+			Addr:     profileAddr(p.rc),
+			Password: p.rc.Password,
+			Timeout:  time.Duration(p.rc.TimeoutSec) * time.Second,
+			Options:  connOpts,
+		})
+	}
+
+	return targets, nil
+}
+
+type namedRC struct {
+	name string
+	rc   config.RC
+}
+
+// loadProfiles resolves rc profiles with CLI/env values as defaults for
+// settings the profile leaves empty.
+func loadProfiles(opts *Options, names []string) ([]namedRC, error) {
+	f, ok, err := config.LoadRCFile(opts.Resources.RCPath)
+	if err != nil {
+		return nil, fmt.Errorf("rc: %w", err)
+	}
+
+	if !ok || len(f.Profiles) == 0 {
+		if len(names) > 0 {
+			return nil, fmt.Errorf("rc: no profiles found")
+		}
+		if opts.Conn.Password == "" {
+			return nil, fmt.Errorf("no rc profiles found and no RCON password specified")
+		}
+
+		return []namedRC{{name: "default", rc: withDefaults(opts, config.RC{})}}, nil
+	}
+
+	switch {
+	case len(names) > 0:
+	case opts.Conn.Profile != "":
+		names = []string{opts.Conn.Profile}
+	default:
+		names = f.ProfileNames()
+	}
+
+	out := make([]namedRC, 0, len(names))
+	for _, name := range names {
+		rc, err := f.Resolve(name)
+		if err != nil {
+			return nil, fmt.Errorf("rc: %w", err)
+		}
+
+		rc = withDefaults(opts, rc)
+		if rc.Password == "" {
+			return nil, fmt.Errorf("rc: profile %s: RCON password must be specified", name)
+		}
+
+		out = append(out, namedRC{name: name, rc: rc})
+	}
+
+	return out, nil
+}
+
+// withDefaults fills empty rc settings from the CLI/env options.
+func withDefaults(opts *Options, rc config.RC) config.RC {
+	if rc.IP == "" {
+		rc.IP = opts.Conn.IP
+	}
+	if rc.IP == "" {
+		rc.IP = "127.0.0.1"
+	}
+	if rc.Port == 0 {
+		rc.Port = opts.Conn.Port
+	}
+	if rc.Port == 0 {
+		rc.Port = 2305
+	}
+	if rc.Password == "" {
+		rc.Password = opts.Conn.Password
+	}
+	if rc.TimeoutSec == 0 {
+		rc.TimeoutSec = opts.Conn.Timeout
+	}
+	if rc.BufferSize == 0 {
+		rc.BufferSize = opts.Conn.Buffer
+	}
+	if rc.GeoDB == "" {
+		rc.GeoDB = opts.Resources.GeoDB
+	}
+	if rc.Relay == "" {
+		rc.Relay = opts.Relay.Addr
+	}
+	if rc.RelayCert == "" {
+		rc.RelayCert = opts.Relay.Cert
+	}
+	if rc.RelayKey == "" {
+		rc.RelayKey = opts.Relay.Key
+	}
+	if rc.RelayCA == "" {
+		rc.RelayCA = opts.Relay.CA
+	}
+
+	return rc
+}
+
+// profileConnOptions returns connection options for a long-lived profile
+// connection, dialed through the relay agent of the profile if any.
+func profileConnOptions(opts *Options, rc config.RC) ([]bercon.Option, error) {
+	dial, err := relayDialer(rc.Relay, rc.RelayCert, rc.RelayKey, rc.RelayCA)
+	if err != nil {
+		return nil, err
+	}
+
+	return []bercon.Option{
+		bercon.WithDeadline(time.Duration(rc.TimeoutSec) * time.Second),
+		withBodySize(rc.BufferSize),
+		bercon.WithLoginAttempts(opts.Conn.LoginAttempts),
+		bercon.WithKeepalive(time.Duration(opts.Repeat.Keepalive) * time.Second),
+		bercon.WithDialer(dial),
+	}, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"syscall"
+
+	"github.com/woozymasta/bercon-cli/internal/printer"
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+type ListenOptions struct {
+	Filter string   `long:"filter"     env:"FILTER"                    description:"Only stream messages matching this regular expression"`
+	Types  []string `long:"type"       env:"TYPE"   env-delim:","      description:"Only stream messages of this event type (repeatable)" choice:"connected" choice:"guid" choice:"disconnected" choice:"kicked" choice:"banned" choice:"filter_kick" choice:"chat" choice:"admin_chat" choice:"admin_login" choice:"unknown"`
+	Listen bool     `short:"L" long:"listen" env:"LISTEN"                description:"Keep the session open and stream server messages until interrupted"`
+}
+
+// eventMatcher builds a predicate from the --filter and --type options.
+func eventMatcher(opts ListenOptions) (func(beparser.Event) bool, error) {
+	var re *regexp.Regexp
+	if opts.Filter != "" {
+		var err error
+		if re, err = regexp.Compile(opts.Filter); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+
+	return func(ev beparser.Event) bool {
+		if len(opts.Types) > 0 && !slices.Contains(opts.Types, string(ev.Type)) {
+			return false
+		}
+		return re == nil || re.MatchString(ev.Raw)
+	}, nil
+}
+
+// listen streams server messages to stdout until SIGINT/SIGTERM or until
+// the connection stops on its own, in which case its error is returned.
+func listen(conn *bercon.Connection, opts ListenOptions, format printer.Format) error {
+	match, err := eventMatcher(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return streamEvents(ctx, os.Stdout, conn, match, format)
+}
+
+// streamEvents prints server messages accepted by match to w until ctx is
+// done or the connection stops. It returns the connection error when the
+// connection stopped for good, for example after reconnecting failed.
+func streamEvents(ctx context.Context, w io.Writer, conn *bercon.Connection, match func(beparser.Event) bool, format printer.Format) error {
+	sub := conn.Subscribe(bercon.IsMessage, bercon.DefaultMessagesBufferSize, bercon.Block)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case pe, ok := <-sub.C:
+			if !ok {
+				return conn.Err()
+			}
+
+			ev := beparser.ParseEvent(pe.Data)
+			if !match(ev) {
+				continue
+			}
+			if err := printer.PrintEvent(w, pe.Time, ev, format); err != nil {
+				return err
+			}
+		}
+	}
+}
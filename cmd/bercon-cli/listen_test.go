@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/internal/printer"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+	"github.com/woozymasta/bercon-cli/pkg/bercon/bercontest"
+)
+
+func TestStreamEventsConnectionLost(t *testing.T) {
+	srv, conn := bercontest.Open(t, "pw",
+		bercon.WithKeepalive(200*time.Millisecond),
+		bercon.WithDeadline(200*time.Millisecond),
+		bercon.WithReconnect(bercon.ReconnectPolicy{
+			Backoff:     20 * time.Millisecond,
+			MaxAttempts: 2,
+		}),
+	)
+
+	match, err := eventMatcher(ListenOptions{Types: []string{"chat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() { done <- streamEvents(context.Background(), &out, conn, match, printer.FormatJSON) }()
+
+	srv.Push("Player #0 Survivor (192.0.2.10:2304) connected")
+	srv.Push("(Global) Survivor: hi")
+	if !srv.WaitAcked(2 * time.Second) {
+		t.Fatal("messages not acked")
+	}
+	_ = srv.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, bercon.ErrReconnectFailed) {
+			t.Fatalf("got %v, want %v", err, bercon.ErrReconnectFailed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still running after the connection stopped")
+	}
+
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], "Survivor: hi") {
+		t.Fatalf("got output %q, want the chat line only", out.String())
+	}
+}
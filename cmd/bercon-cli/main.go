@@ -26,7 +26,7 @@ type ConnectionOptions struct {
 	Password      string `short:"P" long:"password"    env:"PASSWORD"                         description:"Server RCON password"`
 	Profile       string `short:"n" long:"profile"     env:"PROFILE"                          description:"Profile name from rc file"`
 	Timeout       int    `short:"t" long:"timeout"     env:"TIMEOUT"     default:"3"          description:"Deadline and timeout in seconds"`
-	Buffer        uint16 `short:"b" long:"buffer-size" env:"BUFFER_SIZE" default:"1024"       description:"Buffer size for RCON connection"`
+	Buffer        uint16 `short:"b" long:"buffer-size" env:"BUFFER_SIZE" default:"1024"       description:"Max response body size per RCON packet"`
 	LoginAttempts int    `short:"a" long:"attempts"    env:"ATTEMPTS"    default:"1"          description:"Number of login attempts"`
 }
 
@@ -41,6 +41,7 @@ type ResourceOptions struct {
 	RCPath string `short:"c" long:"config"  env:"CONFIG"  description:"Path to rc file (INI). If not set, standard locations are used"`
 	BeCfg  string `short:"r" long:"server-cfg" env:"SERVER_CFG" description:"Path to beserver_x64.cfg file or directory to search"`
 	GeoDB  string `short:"g" long:"geo-db"     env:"GEO_DB"     description:"Path to Country GeoDB mmdb file"`
+	Trace  string `long:"trace"                env:"TRACE"      description:"Write raw packet trace (NDJSON) to file"`
 }
 
 type OutputOptions struct {
@@ -65,6 +66,8 @@ type Options struct {
 	Repeat    RepeatOptions     `group:"Repeat Settings" env-namespace:"BERCON"`
 	Resources ResourceOptions   `group:"File Resources" env-namespace:"BERCON"`
 	Output    OutputOptions     `group:"Output Formatting" env-namespace:"BERCON"`
+	Listen    ListenOptions     `group:"Listen Mode" env-namespace:"BERCON"`
+	Relay     RelayOptions      `group:"Relay Client" env-namespace:"BERCON"`
 	Utility   UtilityOptions    `group:"Utility Commands" env-namespace:"BERCON"`
 	Info      InfoOptions       `group:"Informational" env-namespace:"BERCON"`
 }
@@ -72,7 +75,7 @@ type Options struct {
 func main() {
 	opts := &Options{}
 	p := flags.NewParser(opts, flags.PassDoubleDash|flags.PrintErrors|flags.PassAfterNonOption)
-	p.Usage = "[OPTIONS] command [command, ...]"
+	p.Usage = "[OPTIONS] [shell | exporter | serve | proxy | agent | command [command, ...]]"
 	p.LongDescription = longDescription()
 	p.Name = filepath.Base(p.Name)
 
@@ -108,6 +111,28 @@ func main() {
 		return
 	}
 
+	// server modes resolve rc profiles themselves
+	if len(args) > 0 {
+		var run func(*Options, []string) error
+		switch args[0] {
+		case "exporter":
+			run = runExporter
+		case "serve":
+			run = runServe
+		case "proxy":
+			run = runProxy
+		case "agent":
+			run = runAgent
+		}
+
+		if run != nil {
+			if err := run(opts, args[1:]); err != nil {
+				fatalf("%s: %v", args[0], err)
+			}
+			return
+		}
+	}
+
 	if rc, ok, err := config.LoadRC(opts.Resources.RCPath, opts.Conn.Profile); err != nil {
 		fatalf("rc: %v", err)
 	} else if ok {
@@ -136,6 +161,19 @@ func main() {
 			opts.Conn.Password = rc.Password
 		}
 
+		if opts.Relay.Addr == "" && rc.Relay != "" {
+			opts.Relay.Addr = rc.Relay
+		}
+		if opts.Relay.Cert == "" && rc.RelayCert != "" {
+			opts.Relay.Cert = rc.RelayCert
+		}
+		if opts.Relay.Key == "" && rc.RelayKey != "" {
+			opts.Relay.Key = rc.RelayKey
+		}
+		if opts.Relay.CA == "" && rc.RelayCA != "" {
+			opts.Relay.CA = rc.RelayCA
+		}
+
 		// if profile provided server_cfg – treat as BeCfg input
 		if opts.Resources.BeCfg == "" && rc.ServerCfg != "" {
 			opts.Resources.BeCfg = rc.ServerCfg
@@ -165,8 +203,26 @@ func main() {
 		fatalf("RCON password must be specified")
 	}
 
-	if len(args) < 1 {
-		fatalf("Command must be provided")
+	// interactive shell without commands or with the "shell" command
+	shell := !opts.Listen.Listen && (len(args) == 0 || (len(args) == 1 && args[0] == "shell"))
+
+	// check all commands against the rc file ACL before connecting
+	rcACL, closeACL, err := loadACL(opts)
+	if err != nil {
+		fatalf("acl: %v", err)
+	}
+	defer closeACL()
+
+	g, err := newGuard(rcACL)
+	if err != nil {
+		fatalf("acl: %v", err)
+	}
+	if !shell {
+		for _, cmd := range args {
+			if err := g.check(opts.Conn.Profile, cmd); err != nil {
+				fatalf("%v", err)
+			}
+		}
 	}
 
 	if opts.Repeat.RepeatCount == 0 {
@@ -179,8 +235,50 @@ func main() {
 		format = printer.FormatJSON
 	}
 
+	gap := time.Duration(opts.Repeat.LoopSleep) * time.Second
+	if len(args) > 1 {
+		gap = max(gap, time.Duration(opts.Repeat.CmdSleep)*time.Millisecond)
+	}
+
+	// setup bercon params
+	connOpts := []bercon.Option{
+		bercon.WithDeadline(time.Duration(opts.Conn.Timeout) * time.Second),
+		withBodySize(opts.Conn.Buffer),
+		bercon.WithLoginAttempts(opts.Conn.LoginAttempts),
+	}
+
+	// keepalive only for long sessions
+	if opts.Listen.Listen || shell {
+		connOpts = append(connOpts,
+			bercon.WithKeepalive(time.Duration(opts.Repeat.Keepalive)*time.Second),
+			bercon.WithReconnect(bercon.ReconnectPolicy{}))
+	} else if (opts.Repeat.RepeatCount < 0 || opts.Repeat.RepeatCount > 1 || len(args) > 1) &&
+		gap >= bercon.MaxKeepaliveTimeout*time.Second {
+		connOpts = append(connOpts, bercon.WithKeepalive(time.Duration(opts.Repeat.Keepalive)*time.Second))
+	}
+
+	if opts.Resources.Trace != "" {
+		trace, err := os.Create(opts.Resources.Trace)
+		if err != nil {
+			fatalf("trace: %v", err)
+		}
+		defer func() { _ = trace.Close() }()
+
+		connOpts = append(connOpts, bercon.WithTrace(trace))
+	}
+
 	addr := fmt.Sprintf("%s:%d", opts.Conn.IP, opts.Conn.Port)
-	conn, err := bercon.Open(addr, opts.Conn.Password)
+	if opts.Relay.Addr != "" {
+		dial, err := relayDialer(opts.Relay.Addr, opts.Relay.Cert, opts.Relay.Key, opts.Relay.CA)
+		if err != nil {
+			fatalf("relay: %v", err)
+		}
+
+		connOpts = append(connOpts, bercon.WithDialer(dial))
+		addr = opts.Relay.Addr
+	}
+
+	conn, err := bercon.OpenWithOptions(addr, opts.Conn.Password, connOpts...)
 	if err != nil {
 		fatalf("error opening connection: %v", err)
 	}
@@ -190,23 +288,6 @@ func main() {
 		}
 	}()
 
-	// setup bercon params
-	conn.SetDeadlineTimeout(opts.Conn.Timeout)
-	conn.SetBufferSize(opts.Conn.Buffer)
-	conn.SetLoginAttempts(opts.Conn.LoginAttempts)
-
-	gap := time.Duration(opts.Repeat.LoopSleep) * time.Second
-	if len(args) > 1 {
-		gap = max(gap, time.Duration(opts.Repeat.CmdSleep)*time.Millisecond)
-	}
-
-	// keepalive only for long sessions
-	if (opts.Repeat.RepeatCount < 0 || opts.Repeat.RepeatCount > 1 || len(args) > 1) &&
-		gap >= bercon.MaxKeepaliveTimeout*time.Second {
-		conn.SetKeepaliveTimeout(opts.Repeat.Keepalive)
-		conn.StartKeepAlive()
-	}
-
 	runOnce := func() {
 		for idx, cmd := range args {
 			data, err := conn.Send(cmd)
@@ -223,6 +304,28 @@ func main() {
 		}
 	}
 
+	if shell {
+		prompt := opts.Conn.Profile
+		if prompt == "" {
+			prompt = addr
+		}
+		if err := runShell(conn, g, opts.Conn.Profile, prompt, opts.Resources.GeoDB, format); err != nil {
+			_ = conn.Close()
+			fatalf("shell: %v", err)
+		}
+		return
+	}
+
+	// listen mode: run the given commands once, then stream messages
+	if opts.Listen.Listen {
+		runOnce()
+		if err := listen(conn, opts.Listen, format); err != nil {
+			_ = conn.Close()
+			fatalf("listen: %v", err)
+		}
+		return
+	}
+
 	for loop := 0; opts.Repeat.RepeatCount < 0 || loop < opts.Repeat.RepeatCount; loop++ {
 		runOnce()
 
@@ -233,6 +336,17 @@ func main() {
 	}
 }
 
+// withBodySize sets the receive buffer for packet bodies of up to size
+// bytes plus the packet header, so the default of 1024 matches the library
+// default. Zero keeps the library default.
+func withBodySize(size uint16) bercon.Option {
+	if size == 0 {
+		return func(*bercon.Connection) {}
+	}
+
+	return bercon.WithBufferSize(min(size, bercon.MaxCommandBodySize) + bercon.DefaultBufferHeaderSize)
+}
+
 func fatalf(format string, a ...any) {
 	fmt.Fprintf(os.Stderr, format+"\n", a...)
 	os.Exit(1)
@@ -301,5 +415,13 @@ geo_db = /data/geo/GeoLite2.mmdb
 
 [profile.arma3-test]
 server_cfg = C:\Games\Arma3Server\battleye
-timeout = 5`)
+timeout = 5
+
+[profile.dayz-remote]
+# Connect through "bercon-cli agent" on the game host over mutual TLS
+relay = dayz.example.com:2310
+relay_cert = /etc/bercon-cli/admin.crt
+relay_key = /etc/bercon-cli/admin.key
+relay_ca = /etc/bercon-cli/ca.crt
+password = strongPass`)
 }
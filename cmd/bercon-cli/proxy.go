@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/internal/acl"
+	"github.com/woozymasta/bercon-cli/internal/proxy"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+type ProxyOptions struct {
+	Listen  string   `long:"listen"  env:"LISTEN"  default:"127.0.0.1:2306" description:"UDP address to accept RCON clients on"`
+	Profile string   `long:"profile" env:"PROFILE"                          description:"Profile to proxy (default: --profile of the main options)"`
+	Clients []string `long:"client"  env:"CLIENTS" env-delim:","            description:"Downstream client as name:password (repeatable)"`
+}
+
+// runProxy parses proxy options from args and shares one connection to
+// the selected profile with downstream RCON clients until interrupted.
+func runProxy(opts *Options, args []string) error {
+	var cmd struct {
+		Proxy ProxyOptions `group:"Proxy Settings" env-namespace:"BERCON_PROXY"`
+	}
+	if ok, err := parseSubcommand("proxy", &cmd, args); !ok {
+		return err
+	}
+	po := cmd.Proxy
+
+	var names []string
+	if po.Profile != "" {
+		names = []string{po.Profile}
+	}
+	rcs, err := loadProfiles(opts, names)
+	if err != nil {
+		return err
+	}
+	if len(rcs) != 1 {
+		return errors.New("select the profile to proxy with --profile")
+	}
+	profile, rc := rcs[0].name, rcs[0].rc
+
+	rcACL, closeACL, err := loadACL(opts)
+	if err != nil {
+		return err
+	}
+	defer closeACL()
+
+	g, err := newGuard(rcACL)
+	if err != nil {
+		return err
+	}
+	if err := g.check(profile, ""); err != nil {
+		return err
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	auth, err := newProxyAuth(rcACL, profile, po.Clients)
+	if err != nil {
+		return err
+	}
+
+	connOpts, err := profileConnOptions(opts, rc)
+	if err != nil {
+		return err
+	}
+
+	addr := profileAddr(rc)
+	upstream, err := bercon.OpenWithOptions(addr, rc.Password,
+		append(connOpts,
+			bercon.WithReconnect(bercon.ReconnectPolicy{}),
+			bercon.WithLogger(logger.With("upstream", profile)))...)
+	if err != nil {
+		return fmt.Errorf("upstream: %w", err)
+	}
+	defer func() { _ = upstream.Close() }()
+
+	pc, err := net.ListenPacket("udp", po.Listen)
+	if err != nil {
+		return err
+	}
+
+	p := proxy.New(upstream, auth.authenticate)
+	p.SetAuthorizer(auth.authorize)
+	p.SetLogger(logger)
+	p.SetTimeout(time.Duration(rc.TimeoutSec) * time.Second)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("proxy started", "listen", pc.LocalAddr().String(), "profile", profile, "upstream", addr)
+	return p.Serve(ctx, pc)
+}
+
+// proxyAuth admits downstream clients by their --client password or, when
+// the rc file defines roles, by a [token.*] access token, and checks their
+// commands against the ACL. mu guards identities, which gains token
+// identities as clients log in.
+type proxyAuth struct {
+	acl        *acl.ACL
+	identities map[string]acl.Identity
+	passwords  map[string]string
+	profile    string
+	mu         sync.Mutex
+}
+
+// newProxyAuth parses name:password client entries. Passwords must be
+// unique, as they identify the client. Without roles in the rc file at
+// least one client is required.
+func newProxyAuth(a *acl.ACL, profile string, clients []string) (*proxyAuth, error) {
+	pa := &proxyAuth{
+		acl:        a,
+		profile:    profile,
+		passwords:  make(map[string]string, len(clients)),
+		identities: make(map[string]acl.Identity, len(clients)),
+	}
+
+	owners := make(map[string]string, len(clients)) // password -> name
+	for _, entry := range clients {
+		name, password, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || password == "" {
+			return nil, fmt.Errorf("invalid client %q, want name:password", entry)
+		}
+		if _, dup := pa.passwords[name]; dup {
+			return nil, fmt.Errorf("duplicate client %q", name)
+		}
+		if other, dup := owners[password]; dup {
+			return nil, fmt.Errorf("clients %q and %q share a password", other, name)
+		}
+
+		owners[password] = name
+		pa.passwords[name] = password
+		pa.identities[name] = a.User(name)
+	}
+
+	if len(clients) == 0 && !a.Enabled() {
+		return nil, errors.New("no downstream clients: add --client name:password or [token.*] sections to the rc file")
+	}
+
+	return pa, nil
+}
+
+// authenticate maps a login password to a client name. Clients without
+// access to the proxied profile are rejected.
+func (pa *proxyAuth) authenticate(password string) (string, bool) {
+	var name string
+	for n, pw := range pa.passwords {
+		if subtle.ConstantTimeCompare([]byte(pw), []byte(password)) == 1 {
+			name = n
+		}
+	}
+
+	if name == "" {
+		if !pa.acl.Enabled() {
+			return "", false
+		}
+
+		id, err := pa.acl.Token(password)
+		if err != nil {
+			return "", false
+		}
+		name = id.Name
+		pa.setIdentity(name, id)
+	}
+
+	return name, pa.acl.Check(pa.identity(name), pa.profile, "") == nil
+}
+
+// authorize checks a command of a logged-in client against the ACL.
+func (pa *proxyAuth) authorize(client, command string) error {
+	return pa.acl.Check(pa.identity(client), pa.profile, command)
+}
+
+func (pa *proxyAuth) identity(name string) acl.Identity {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	return pa.identities[name]
+}
+
+func (pa *proxyAuth) setIdentity(name string, id acl.Identity) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	pa.identities[name] = id
+}
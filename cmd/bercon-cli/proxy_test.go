@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/woozymasta/bercon-cli/internal/acl"
+	"github.com/woozymasta/bercon-cli/internal/config"
+)
+
+func TestProxyAuthClients(t *testing.T) {
+	a := acl.New(config.ACLConfig{})
+
+	if _, err := newProxyAuth(a, "dayz", []string{"alice:pw", "panel:pw"}); err == nil {
+		t.Fatal("clients sharing a password accepted")
+	}
+
+	pa, err := newProxyAuth(a, "dayz", []string{"alice:pw-a", "panel:pw-p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for password, want := range map[string]string{"pw-a": "alice", "pw-p": "panel"} {
+		if name, ok := pa.authenticate(password); !ok || name != want {
+			t.Errorf("authenticate(%q) = %q, %v; want %q", password, name, ok, want)
+		}
+	}
+	if _, ok := pa.authenticate("wrong"); ok {
+		t.Error("wrong password accepted")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/woozymasta/bercon-cli/internal/config"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+	"github.com/woozymasta/bercon-cli/pkg/berelay"
+)
+
+type RelayOptions struct {
+	Addr string `long:"relay"      env:"RELAY"      description:"Connect through a relay agent at host:port over TLS instead of UDP"`
+	Cert string `long:"relay-cert" env:"RELAY_CERT" description:"Client certificate (PEM) for the relay agent"`
+	Key  string `long:"relay-key"  env:"RELAY_KEY"  description:"Client private key (PEM) for the relay agent"`
+	CA   string `long:"relay-ca"   env:"RELAY_CA"   description:"CA certificate (PEM) to verify the relay agent"`
+}
+
+type AgentOptions struct {
+	Listen string   `long:"listen" env:"LISTEN" default:":2310" description:"TCP address to accept relay clients on"`
+	Target string   `long:"target" env:"TARGET"                 description:"RCON address to relay to (default: --ip and --port, or --server-cfg)"`
+	Cert   string   `long:"cert"   env:"CERT"   required:"true" description:"Agent certificate (PEM)"`
+	Key    string   `long:"key"    env:"KEY"    required:"true" description:"Agent private key (PEM)"`
+	CA     string   `long:"ca"     env:"CA"     required:"true" description:"CA certificate (PEM) that client certificates must be signed by"`
+	Allow  []string `long:"allow"  env:"ALLOW"  env-delim:","   description:"Only admit client certificates with this common or DNS name (repeatable)"`
+}
+
+// runAgent parses agent options from args and relays TLS clients to the
+// local RCON port until interrupted.
+func runAgent(opts *Options, args []string) error {
+	var cmd struct {
+		Agent AgentOptions `group:"Agent Settings" env-namespace:"BERCON_AGENT"`
+	}
+	if ok, err := parseSubcommand("agent", &cmd, args); !ok {
+		return err
+	}
+	ao := cmd.Agent
+
+	target := ao.Target
+	if target == "" {
+		ip, port := opts.Conn.IP, opts.Conn.Port
+		if opts.Resources.BeCfg != "" {
+			rc, err := config.LoadFromBeServerCfg(opts.Resources.BeCfg)
+			if err != nil {
+				return err
+			}
+			ip, port = rc.IP, rc.Port
+		}
+		target = net.JoinHostPort(ip, strconv.Itoa(port))
+	}
+
+	cfg, err := berelay.LoadServerConfig(ao.Cert, ao.Key, ao.CA)
+	if err != nil {
+		return err
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	agent := berelay.NewAgent(target, cfg)
+	agent.SetAllowed(ao.Allow)
+	agent.SetLogger(logger)
+
+	ln, err := net.Listen("tcp", ao.Listen)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("agent started", "listen", ln.Addr().String(), "target", target)
+	return agent.Serve(ctx, ln)
+}
+
+// relayDialer returns a transport through the relay agent, or nil when
+// relay is empty.
+func relayDialer(relay, certFile, keyFile, caFile string) (bercon.DialFunc, error) {
+	if relay == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, errors.New("relay requires a client certificate, key and CA")
+	}
+
+	cfg, err := berelay.LoadClientConfig(certFile, keyFile, caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return berelay.Dialer(cfg), nil
+}
+
+// profileAddr returns the address a profile connection dials: the relay
+// agent when set, otherwise the RCON endpoint.
+func profileAddr(rc config.RC) string {
+	if rc.Relay != "" {
+		return rc.Relay
+	}
+	return net.JoinHostPort(rc.IP, strconv.Itoa(rc.Port))
+}
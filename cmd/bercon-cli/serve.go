@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/woozymasta/bercon-cli/internal/httpapi"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+type ServeOptions struct {
+	Listen   string   `long:"listen"  env:"LISTEN"   default:"127.0.0.1:8080" description:"Address to serve the HTTP API on"`
+	Profiles []string `long:"profile" env:"PROFILES" env-delim:","            description:"Profile to serve (repeatable, default: all rc profiles)"`
+	Replay   int      `long:"replay"  env:"REPLAY"   default:"100"            description:"Recent events kept per profile for replay on the events stream"`
+}
+
+// runServe parses serve options from args and serves the HTTP API for the
+// rc profiles until interrupted.
+func runServe(opts *Options, args []string) error {
+	var cmd struct {
+		Serve ServeOptions `group:"Serve Settings" env-namespace:"BERCON_SERVE"`
+	}
+	if ok, err := parseSubcommand("serve", &cmd, args); !ok {
+		return err
+	}
+	so := cmd.Serve
+
+	rcs, err := loadProfiles(opts, so.Profiles)
+	if err != nil {
+		return err
+	}
+
+	profiles := make([]httpapi.Profile, 0, len(rcs))
+	for _, p := range rcs {
+		connOpts, err := profileConnOptions(opts, p.rc)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", p.name, err)
+		}
+
+		profiles = append(profiles, httpapi.Profile{
+			Name:     p.name,
+			Addr:     profileAddr(p.rc),
+			Password: p.rc.Password,
+			Timeout:  time.Duration(p.rc.TimeoutSec) * time.Second,
+			Options:  append(connOpts, bercon.WithReconnect(bercon.ReconnectPolicy{})),
+		})
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	api := httpapi.New(profiles)
+	api.SetLogger(logger)
+	api.SetReplaySize(so.Replay)
+	defer func() { _ = api.Close() }()
+
+	rcACL, closeACL, err := loadACL(opts)
+	if err != nil {
+		return err
+	}
+	defer closeACL()
+
+	if rcACL.Enabled() {
+		api.SetAuthorizer(func(r *http.Request, profile, command string) error {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			id, err := rcACL.Token(strings.TrimSpace(token))
+			if err != nil {
+				return fmt.Errorf("%w: %w", httpapi.ErrUnauthorized, err)
+			}
+			if err := rcACL.Check(id, profile, command); err != nil {
+				return fmt.Errorf("%w: %w", httpapi.ErrForbidden, err)
+			}
+			return nil
+		})
+	}
+
+	if opts.Resources.GeoDB != "" {
+		geo, err := geoip2.Open(opts.Resources.GeoDB)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = geo.Close() }()
+
+		api.SetGeoReader(geo)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("api started", "listen", so.Listen, "servers", len(profiles))
+	return listenAndServe(ctx, so.Listen, api)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/woozymasta/bercon-cli/internal/config"
+	"github.com/woozymasta/bercon-cli/internal/lineedit"
+	"github.com/woozymasta/bercon-cli/internal/printer"
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+// beCommands are BattlEye RCon commands offered by tab completion.
+var beCommands = []string{
+	"#exec", "#init", "#lock", "#monitor", "#reassign", "#restart",
+	"#shutdown", "#unlock", "addBan", "admins", "ban", "bans", "kick",
+	"loadBans", "loadEvents", "loadScripts", "MaxPing", "missions",
+	"players", "RConPassword", "removeBan", "say", "writeBans",
+}
+
+// shellBuiltins are handled by the shell itself and never sent.
+var shellBuiltins = []string{"exit", "quit"}
+
+// playerCommands take a player ID as their first argument.
+var playerCommands = []string{"ban", "kick", "say"}
+
+// secretCommands take a secret argument and are kept out of the history.
+var secretCommands = []string{"RConPassword"}
+
+// historyFile is the shell history file name in config.Dir.
+const historyFile = "history"
+
+type shell struct {
+	conn    *bercon.Connection
+	editor  *lineedit.Editor
+	guard   *guard
+	profile string
+	geoDB   string
+	players beparser.Players // from the last "players" response
+	mu      sync.Mutex
+	format  printer.Format
+}
+
+// runShell reads commands interactively and prints responses and server
+// messages until exit, Ctrl+D or end of input.
+func runShell(conn *bercon.Connection, g *guard, profile, prompt, geoDB string, format printer.Format) error {
+	sh := &shell{
+		conn:    conn,
+		editor:  lineedit.New(os.Stdin, os.Stdout),
+		guard:   g,
+		profile: profile,
+		geoDB:   geoDB,
+		format:  format,
+	}
+	sh.editor.Completer = sh.complete
+
+	if dir, err := config.Dir(); err == nil {
+		path := filepath.Join(dir, historyFile)
+		if err := sh.editor.LoadHistory(path); err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		}
+		defer func() {
+			if err := sh.editor.SaveHistory(path); err != nil {
+				fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			}
+		}()
+	}
+
+	// print server messages above the prompt
+	sub := conn.Subscribe(bercon.IsMessage, bercon.DefaultMessagesBufferSize, bercon.DropOldest)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for pe := range sub.C {
+			_ = printer.PrintEvent(sh.editor, pe.Time, beparser.ParseEvent(pe.Data), format)
+		}
+	}()
+	defer func() {
+		sub.Unsubscribe()
+		<-done
+	}()
+
+	if sh.editor.IsTerminal() {
+		_, _ = fmt.Fprintln(sh.editor, "Type a command, Tab to complete, exit or Ctrl+D to quit.")
+	}
+
+	for {
+		line, err := sh.editor.ReadLine(prompt + "> ")
+		switch {
+		case errors.Is(err, lineedit.ErrInterrupt):
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !isSecret(line) {
+			sh.editor.AddHistory(line)
+		}
+
+		if isBuiltin(line) {
+			return nil
+		}
+
+		if err := sh.run(line); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		if err := conn.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// run checks and sends a single command and prints its response.
+func (sh *shell) run(line string) error {
+	if err := sh.guard.check(sh.profile, line); err != nil {
+		return err
+	}
+
+	data, err := sh.conn.Send(line)
+	if err != nil {
+		return fmt.Errorf("error in command '%s': %w", line, err)
+	}
+
+	if strings.EqualFold(strings.TrimSpace(line), "players") {
+		p := beparser.NewPlayers()
+		p.Parse(data)
+
+		sh.mu.Lock()
+		sh.players = *p
+		sh.mu.Unlock()
+	}
+
+	if err := printer.ParseAndPrintData(sh.editor, data, line, sh.geoDB, sh.format); err != nil {
+		return fmt.Errorf("cant print response data: %w", err)
+	}
+
+	return nil
+}
+
+// complete offers commands for the first word and player IDs, matched by
+// ID or name, for the first argument of player commands.
+func (sh *shell) complete(head string) []lineedit.Candidate {
+	fields := strings.Fields(head)
+	if strings.HasSuffix(head, " ") || len(fields) == 0 {
+		fields = append(fields, "")
+	}
+	word := fields[len(fields)-1]
+
+	var out []lineedit.Candidate
+	switch len(fields) {
+	case 1:
+		for _, c := range slices.Concat(beCommands, shellBuiltins) {
+			if hasPrefixFold(c, word) {
+				out = append(out, lineedit.Candidate{Text: c})
+			}
+		}
+
+	case 2:
+		if !containsFold(playerCommands, fields[0]) {
+			return nil
+		}
+
+		sh.mu.Lock()
+		defer sh.mu.Unlock()
+
+		for _, p := range sh.players {
+			id := strconv.Itoa(int(p.ID))
+			if strings.HasPrefix(id, word) || hasPrefixFold(p.Name, word) {
+				out = append(out, lineedit.Candidate{Text: id, Display: id + ":" + p.Name})
+			}
+		}
+	}
+
+	return out
+}
+
+func isBuiltin(line string) bool {
+	return containsFold(shellBuiltins, line)
+}
+
+func isSecret(line string) bool {
+	name, _, _ := strings.Cut(line, " ")
+	return containsFold(secretCommands, name)
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
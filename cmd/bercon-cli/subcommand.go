@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// parseSubcommand parses the options of a subcommand from args into data,
+// a struct with option groups. It reports false when help was printed.
+func parseSubcommand(name string, data any, args []string) (bool, error) {
+	p := flags.NewNamedParser("bercon-cli [OPTIONS] "+name, flags.Default)
+	if _, err := p.AddGroup("", "", data); err != nil {
+		return false, err
+	}
+
+	if _, err := p.ParseArgs(args); err != nil {
+		if flags.WroteHelp(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// listenAndServe serves h on addr until ctx is done, then shuts the server
+// down gracefully.
+func listenAndServe(ctx context.Context, addr string, h http.Handler) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
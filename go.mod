@@ -7,6 +7,7 @@ require (
 	github.com/jessevdk/go-flags v1.6.1
 	github.com/oschwald/geoip2-golang v1.13.0
 	github.com/woozymasta/dzid v0.1.0
+	golang.org/x/sys v0.39.0
 	gopkg.in/ini.v1 v1.67.1
 )
 
@@ -14,6 +15,5 @@ require (
 	github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.19 // indirect
 	github.com/oschwald/maxminddb-golang v1.13.1 // indirect
-	golang.org/x/sys v0.39.0 // indirect
 	golang.org/x/text v0.32.0 // indirect
 )
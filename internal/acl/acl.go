@@ -0,0 +1,201 @@
+/*
+Package acl checks RCON commands against role-based permissions from the
+rc file.
+
+Identities are OS users or access tokens, each mapped to roles. A role
+allows commands matching its patterns on profiles matching its profile
+patterns. Patterns are matched case-insensitively against the whole
+command line (or profile name); '*' matches any text, including none,
+and '?' a single character. Everything not allowed is denied, and
+denials are logged.
+*/
+package acl
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/user"
+	"slices"
+	"strings"
+
+	"github.com/woozymasta/bercon-cli/internal/config"
+)
+
+var (
+	// ErrDenied is returned for commands not allowed to the identity.
+	ErrDenied = errors.New("permission denied")
+
+	// ErrUnknownToken is returned for a token not defined in the rc file.
+	ErrUnknownToken = errors.New("unknown access token")
+)
+
+// Identity is the principal commands are checked for.
+type Identity struct {
+	Name  string   // "user:<name>" or "token:<section name>"
+	Roles []string // role names from the rc file
+}
+
+// ACL is a compiled set of roles, users and tokens.
+type ACL struct {
+	logger *slog.Logger
+	cfg    config.ACLConfig
+}
+
+// New returns an ACL for cfg. When cfg has no roles, the ACL is disabled
+// and allows everything.
+func New(cfg config.ACLConfig) *ACL {
+	return &ACL{cfg: cfg, logger: slog.New(slog.DiscardHandler)}
+}
+
+// SetLogger sets the logger for denials. nil disables logging.
+func (a *ACL) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	a.logger = l
+}
+
+// Enabled reports whether any role is defined.
+func (a *ACL) Enabled() bool {
+	return a != nil && a.cfg.Enabled()
+}
+
+// User returns the identity of an OS user. On Windows both the
+// DOMAIN\name form and the bare name are looked up.
+func (a *ACL) User(name string) Identity {
+	roles, ok := a.cfg.Users[name]
+	if !ok {
+		if i := strings.LastIndexByte(name, '\\'); i >= 0 {
+			name = name[i+1:]
+			roles = a.cfg.Users[name]
+		}
+	}
+
+	return Identity{Name: "user:" + name, Roles: roles}
+}
+
+// CurrentUser returns the identity of the OS user running the process.
+func (a *ACL) CurrentUser() (Identity, error) {
+	u, err := user.Current()
+	if err != nil {
+		return Identity{}, err
+	}
+
+	return a.User(u.Username), nil
+}
+
+// Token returns the identity of an access token. Unknown tokens are
+// logged like denials.
+func (a *ACL) Token(token string) (Identity, error) {
+	sum := sha256.Sum256([]byte(token))
+	digest := hex.EncodeToString(sum[:])
+
+	for name, t := range a.cfg.Tokens {
+		var match bool
+		switch {
+		case t.SHA256 != "":
+			match = subtle.ConstantTimeCompare([]byte(t.SHA256), []byte(digest)) == 1
+		case t.Token != "":
+			match = subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1
+		}
+
+		if match {
+			return Identity{Name: "token:" + name, Roles: t.Roles}, nil
+		}
+	}
+
+	a.logger.Warn("unknown access token")
+	return Identity{}, ErrUnknownToken
+}
+
+// Identify returns the token identity when token is set and the current
+// OS user identity otherwise.
+func (a *ACL) Identify(token string) (Identity, error) {
+	if token != "" {
+		return a.Token(token)
+	}
+
+	return a.CurrentUser()
+}
+
+// Allowed reports whether id may run command on profile.
+func (a *ACL) Allowed(id Identity, profile, command string) bool {
+	if !a.Enabled() {
+		return true
+	}
+
+	command = strings.Join(strings.Fields(command), " ")
+	for _, name := range id.Roles {
+		role, ok := a.cfg.Roles[name]
+		if !ok {
+			continue
+		}
+
+		if len(role.Profiles) > 0 && !matchAny(role.Profiles, profile) {
+			continue
+		}
+		if command == "" || matchAny(role.Commands, command) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check returns an error wrapping ErrDenied and logs the denial when id
+// may not run command on profile. An empty command checks access to the
+// profile only.
+func (a *ACL) Check(id Identity, profile, command string) error {
+	if a.Allowed(id, profile, command) {
+		return nil
+	}
+
+	a.logger.Warn("command denied",
+		"identity", id.Name, "roles", id.Roles, "profile", profile, "command", command)
+
+	if command == "" {
+		return fmt.Errorf("%w: %s may not access profile %q", ErrDenied, id.Name, profile)
+	}
+	return fmt.Errorf("%w: %s may not run %q on profile %q", ErrDenied, id.Name, command, profile)
+}
+
+func matchAny(patterns []string, s string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool { return Match(p, s) })
+}
+
+// Match reports whether s matches the glob pattern case-insensitively.
+// '*' matches any text, including none, and '?' a single character.
+func Match(pattern, s string) bool {
+	p := []rune(strings.ToLower(pattern))
+	r := []rune(strings.ToLower(s))
+
+	// iterative glob matching with backtracking to the last '*'
+	var pi, ri int
+	star, mark := -1, 0
+	for ri < len(r) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == r[ri]):
+			pi++
+			ri++
+		case pi < len(p) && p[pi] == '*':
+			star, mark = pi, ri
+			pi++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			ri = mark
+		default:
+			return false
+		}
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+
+	return pi == len(p)
+}
@@ -0,0 +1,118 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/woozymasta/bercon-cli/internal/config"
+)
+
+func testACL() *ACL {
+	sum := sha256.Sum256([]byte("panel-secret"))
+
+	return New(config.ACLConfig{
+		Roles: map[string]config.RoleRC{
+			"moderator": {Commands: []string{"players", "kick *", "say *"}, Profiles: []string{"dayz-*"}},
+			"admin":     {Commands: []string{"*"}},
+		},
+		Users: map[string][]string{
+			"alice": {"moderator"},
+			"root":  {"admin"},
+		},
+		Tokens: map[string]config.TokenRC{
+			"panel": {SHA256: hex.EncodeToString(sum[:]), Roles: []string{"moderator"}},
+			"ci":    {Token: "plain", Roles: []string{"admin"}},
+		},
+	})
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"players", "players", true},
+		{"players", "Players", true},
+		{"players", "players x", false},
+		{"kick *", "kick 3 bye", true},
+		{"kick *", "kick", false},
+		{"say *", "say -1 see http://x/y", true},
+		{"#*lock", "#unlock", true},
+		{"dayz-*", "dayz-eu", true},
+		{"dayz-??", "dayz-eu", true},
+		{"dayz-??", "dayz-eu1", false},
+		{"*", "", true},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+	}
+
+	for _, c := range cases {
+		if got := Match(c.pattern, c.s); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.s, got, c.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	a := testACL()
+
+	alice := a.User("alice")
+	for _, cmd := range []string{"players", "kick 3 spam", "say  -1   hi"} {
+		if err := a.Check(alice, "dayz-eu", cmd); err != nil {
+			t.Errorf("alice %q: %v", cmd, err)
+		}
+	}
+	for _, cmd := range []string{"#shutdown", "loadBans", "removeBan 1"} {
+		if err := a.Check(alice, "dayz-eu", cmd); !errors.Is(err, ErrDenied) {
+			t.Errorf("alice %q: got %v, want denied", cmd, err)
+		}
+	}
+	if a.Allowed(alice, "arma3", "players") {
+		t.Error("alice allowed on a foreign profile")
+	}
+	if !a.Allowed(alice, "dayz-eu", "") || a.Allowed(alice, "arma3", "") {
+		t.Error("profile-only access check")
+	}
+
+	if !a.Allowed(a.User(`HOST\root`), "arma3", "#shutdown") {
+		t.Error("domain user not resolved")
+	}
+	if a.Allowed(a.User("mallory"), "dayz-eu", "players") {
+		t.Error("unknown user allowed")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	a := testACL()
+
+	panel, err := a.Token("panel-secret")
+	if err != nil || panel.Name != "token:panel" {
+		t.Fatalf("hashed token: %+v, %v", panel, err)
+	}
+	if a.Allowed(panel, "dayz-eu", "#shutdown") {
+		t.Error("moderator token allowed #shutdown")
+	}
+
+	ci, err := a.Identify("plain")
+	if err != nil || !a.Allowed(ci, "any", "#shutdown") {
+		t.Fatalf("plain token: %+v, %v", ci, err)
+	}
+
+	if _, err := a.Token("wrong"); !errors.Is(err, ErrUnknownToken) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	a := New(config.ACLConfig{})
+	if a.Enabled() || !a.Allowed(Identity{}, "any", "#shutdown") {
+		t.Fatal("empty ACL must allow everything")
+	}
+
+	var nilACL *ACL
+	if nilACL.Enabled() {
+		t.Fatal("nil ACL enabled")
+	}
+}
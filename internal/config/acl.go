@@ -0,0 +1,94 @@
+package config
+
+import (
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// ACLConfig is the access control part of the rc file:
+//
+//	[acl]
+//	log = /var/log/bercon-acl.log   ; optional denial log, stderr if empty
+//
+//	[role.moderator]
+//	commands = players, kick *, say *
+//	profiles = dayz-*
+//
+//	[user.alice]                    ; OS user name
+//	roles = moderator
+//
+//	[token.panel]                   ; matched against BERCON_TOKEN
+//	token_sha256 = 9f86d08...       ; or token = plain value
+//	roles = moderator
+type ACLConfig struct {
+	Roles  map[string]RoleRC
+	Users  map[string][]string // OS user name -> roles
+	Tokens map[string]TokenRC  // token section name -> token
+	Log    string              // denial log file
+}
+
+// RoleRC lists command and profile patterns allowed for a role.
+type RoleRC struct {
+	Commands []string
+	Profiles []string // empty allows all profiles
+}
+
+// TokenRC is an access token mapped to roles. Exactly one of Token and
+// SHA256 (hex digest of the token) is expected to be set.
+type TokenRC struct {
+	Token  string
+	SHA256 string
+	Roles  []string
+}
+
+// Enabled reports whether any role is defined. Without roles the rc file
+// has no access control and every command is allowed.
+func (a ACLConfig) Enabled() bool {
+	return len(a.Roles) > 0
+}
+
+// readACL reads [acl], [role.*], [user.*] and [token.*] sections.
+func readACL(cfg *ini.File) ACLConfig {
+	a := ACLConfig{
+		Roles:  make(map[string]RoleRC),
+		Users:  make(map[string][]string),
+		Tokens: make(map[string]TokenRC),
+		Log:    cfg.Section("acl").Key("log").String(),
+	}
+
+	for _, sec := range cfg.Sections() {
+		name := sec.Name()
+		switch {
+		case strings.HasPrefix(name, "role."):
+			a.Roles[strings.TrimPrefix(name, "role.")] = RoleRC{
+				Commands: listKey(sec, "commands"),
+				Profiles: listKey(sec, "profiles"),
+			}
+
+		case strings.HasPrefix(name, "user."):
+			a.Users[strings.TrimPrefix(name, "user.")] = listKey(sec, "roles")
+
+		case strings.HasPrefix(name, "token."):
+			a.Tokens[strings.TrimPrefix(name, "token.")] = TokenRC{
+				Token:  sec.Key("token").String(),
+				SHA256: strings.ToLower(sec.Key("token_sha256").String()),
+				Roles:  listKey(sec, "roles"),
+			}
+		}
+	}
+
+	return a
+}
+
+// listKey returns the non-empty comma separated values of a key.
+func listKey(sec *ini.Section, key string) []string {
+	var out []string
+	for _, v := range sec.Key(key).Strings(",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the per-user bercon-cli directory for state such as shell
+// history: %APPDATA%\bercon-cli on Windows,
+// ~/Library/Application Support/bercon-cli on macOS and
+// $XDG_CONFIG_HOME/bercon-cli or ~/.config/bercon-cli elsewhere.
+func Dir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(base, "bercon-cli"), nil
+}
@@ -9,6 +9,10 @@ It supports:
   - Resolving RC config file locations automatically based on OS conventions
     (e.g. ~/.config/bercon-cli/config.ini, %APPDATA%\bercon-cli\config.ini, etc).
   - Listing available profiles and printing them in a table-friendly format.
+  - Reading relay agent settings (relay, relay_cert, relay_key, relay_ca)
+    for connections over TLS instead of UDP.
+  - Reading role-based access control ([role.*], [user.*], [token.*]).
+  - Locating the per-user bercon-cli directory for state like shell history.
 
 When multiple sources are provided, the precedence is:
 CLI > Environment > RC file > beserver_x64*.cfg (for connection parameters only).
@@ -16,6 +16,10 @@ type RC struct {
 	ServerCfg  string
 	GeoDB      string
 	Format     string
+	Relay      string
+	RelayCert  string
+	RelayKey   string
+	RelayCA    string
 	Port       int
 	TimeoutSec int
 	BufferSize uint16
@@ -53,6 +57,18 @@ func readSectionInto(dst *RC, s *ini.Section) {
 	if k := s.Key("format"); k != nil {
 		dst.Format = k.String()
 	}
+	if k := s.Key("relay"); k != nil {
+		dst.Relay = k.String()
+	}
+	if k := s.Key("relay_cert"); k != nil {
+		dst.RelayCert = k.String()
+	}
+	if k := s.Key("relay_key"); k != nil {
+		dst.RelayKey = k.String()
+	}
+	if k := s.Key("relay_ca"); k != nil {
+		dst.RelayCA = k.String()
+	}
 	if k := s.Key("timeout"); k != nil {
 		if v, _ := k.Int(); v > 0 {
 			dst.TimeoutSec = v
@@ -79,6 +95,18 @@ func mergeRC(base, over RC) RC {
 	if over.ServerCfg != "" {
 		base.ServerCfg = over.ServerCfg
 	}
+	if over.Relay != "" {
+		base.Relay = over.Relay
+	}
+	if over.RelayCert != "" {
+		base.RelayCert = over.RelayCert
+	}
+	if over.RelayKey != "" {
+		base.RelayKey = over.RelayKey
+	}
+	if over.RelayCA != "" {
+		base.RelayCA = over.RelayCA
+	}
 	// misc
 	if over.GeoDB != "" {
 		base.GeoDB = over.GeoDB
@@ -11,6 +11,7 @@ import (
 // RCFile represents parsed rc file with globals and profiles.
 type RCFile struct {
 	Profiles map[string]RC
+	ACL      ACLConfig
 	Path     string
 	Globals  RC
 }
@@ -57,6 +58,7 @@ func LoadRCFile(explicitPath string) (*RCFile, bool, error) {
 	f := &RCFile{
 		Path:     path,
 		Profiles: make(map[string]RC),
+		ACL:      readACL(cfg),
 	}
 	// read globals
 	readSectionInto(&f.Globals, cfg.Section("globals"))
@@ -74,3 +76,21 @@ func LoadRCFile(explicitPath string) (*RCFile, bool, error) {
 
 	return f, true, nil
 }
+
+// Resolve returns Effective(profile) with IP, Port and Password taken from
+// the profile's server_cfg when it is set, the same precedence the CLI
+// applies to a single profile.
+func (f *RCFile) Resolve(profile string) (RC, error) {
+	rc, err := f.Effective(profile)
+	if err != nil || rc.ServerCfg == "" {
+		return rc, err
+	}
+
+	be, err := LoadFromBeServerCfg(rc.ServerCfg)
+	if err != nil {
+		return RC{}, fmt.Errorf("profile %s: %w", profile, err)
+	}
+	rc.IP, rc.Port, rc.Password = be.IP, be.Port, be.Password
+
+	return rc, nil
+}
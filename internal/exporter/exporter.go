@@ -0,0 +1,213 @@
+/*
+Package exporter polls BattlEye RCON servers and exposes their state as
+Prometheus metrics in the text exposition format.
+
+Each target keeps one long-lived connection that is reopened after a
+failure. Every poll sends "players", "bans" and "admins", parses the
+responses with beparser and replaces the target snapshot served on
+/metrics, so a scrape never waits for RCON.
+*/
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+// DefaultInterval is the poll interval used when none is set.
+const DefaultInterval = 15 * time.Second
+
+// Commands are sent to every target on each poll, in this order.
+var Commands = []string{"players", "bans", "admins"}
+
+// Target is a server to poll.
+type Target struct {
+	Name     string          // "server" label value, usually the rc profile name
+	Addr     string          // host:port of the RCON endpoint
+	Password string          // RCON password
+	Options  []bercon.Option // applied to every (re)opened connection
+	Timeout  time.Duration   // per-command timeout; the connection deadline when zero
+}
+
+// Exporter polls targets and serves their metrics over HTTP.
+type Exporter struct {
+	logger   *slog.Logger
+	targets  []*target
+	interval time.Duration
+}
+
+// target is a polled server with its connection and last snapshot.
+// conn is used only by the polling goroutine, mu guards snap.
+type target struct {
+	conn *bercon.Connection
+	snap snapshot
+	Target
+	mu sync.Mutex
+}
+
+// snapshot is the state of a target at the end of a poll.
+type snapshot struct {
+	polledAt    time.Time
+	lastSuccess time.Time
+	bans        beparser.Bans
+	players     beparser.Players
+	admins      beparser.Admins
+	stats       bercon.Stats
+	duration    time.Duration
+	up          bool
+}
+
+// New returns an exporter for targets polled every interval
+// (DefaultInterval when interval <= 0).
+func New(targets []Target, interval time.Duration) *Exporter {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	e := &Exporter{
+		interval: interval,
+		logger:   slog.New(slog.DiscardHandler),
+	}
+	for _, t := range targets {
+		e.targets = append(e.targets, &target{Target: t})
+	}
+
+	return e
+}
+
+// SetLogger sets the logger for poll failures. nil disables logging.
+func (e *Exporter) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	e.logger = l
+}
+
+// Run polls all targets immediately and then every interval until ctx is
+// done, then closes their connections.
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	defer e.close()
+
+	for {
+		e.Poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll polls all targets concurrently once. It must not be called
+// concurrently with Run or another Poll.
+func (e *Exporter) Poll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range e.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.poll(ctx, e.logger)
+		}()
+	}
+	wg.Wait()
+}
+
+func (e *Exporter) close() {
+	for _, t := range e.targets {
+		if t.conn != nil {
+			_ = t.conn.Close()
+			t.conn = nil
+		}
+	}
+}
+
+// poll refreshes the target snapshot. Any failure marks the target down
+// and drops the connection, which is reopened on the next poll.
+func (t *target) poll(ctx context.Context, log *slog.Logger) {
+	start := time.Now()
+	snap := snapshot{polledAt: start, lastSuccess: t.snapshot().lastSuccess}
+
+	err := t.collect(ctx, &snap)
+	snap.duration = time.Since(start)
+	if err != nil {
+		log.Warn("poll failed", "server", t.Name, "addr", t.Addr, "err", err)
+		if t.conn != nil {
+			snap.stats = t.conn.Stats()
+			_ = t.conn.Close()
+			t.conn = nil
+		}
+	} else {
+		snap.up = true
+		snap.lastSuccess = time.Now()
+		snap.stats = t.conn.Stats()
+	}
+
+	t.mu.Lock()
+	t.snap = snap
+	t.mu.Unlock()
+}
+
+func (t *target) snapshot() snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.snap
+}
+
+func (t *target) collect(ctx context.Context, snap *snapshot) error {
+	if t.conn != nil && t.conn.Err() != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+
+	if t.conn == nil {
+		conn, err := bercon.OpenWithOptions(t.Addr, t.Password, t.Options...)
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+	}
+
+	for _, cmd := range Commands {
+		parsed, err := t.send(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		switch x := parsed.(type) {
+		case *beparser.Players:
+			snap.players = *x
+		case *beparser.Bans:
+			snap.bans = *x
+		case *beparser.Admins:
+			snap.admins = *x
+		}
+	}
+
+	return nil
+}
+
+// send runs a command and parses the response.
+func (t *target) send(ctx context.Context, cmd string) (any, error) {
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	data, err := t.conn.SendContext(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cmd, err)
+	}
+
+	return beparser.Parse(data, cmd), nil
+}
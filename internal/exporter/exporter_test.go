@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+	"github.com/woozymasta/bercon-cli/pkg/bercon/bercontest"
+)
+
+func TestExporterMetrics(t *testing.T) {
+	srv := bercontest.NewServer("pw")
+	defer func() { _ = srv.Close() }()
+
+	// a port nobody answers on
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = silent.Close() }()
+
+	opts := []bercon.Option{bercon.WithDeadline(300 * time.Millisecond)}
+	e := New([]Target{
+		{Name: "local", Addr: srv.Addr, Password: "pw", Options: opts},
+		{Name: "down", Addr: silent.LocalAddr().String(), Password: "pw", Options: opts},
+	}, time.Minute)
+	defer e.close()
+
+	e.Poll(context.Background())
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE bercon_up gauge",
+		`bercon_up{server="local"} 1`,
+		`bercon_up{server="down"} 0`,
+		`bercon_players_online{server="local"} 4`,
+		`bercon_players{server="local",state="lobby"} 1`,
+		`bercon_players{server="local",state="ingame"} 3`,
+		`bercon_players_invalid_guid{server="local"} 1`,
+		"# TYPE bercon_player_ping_seconds histogram",
+		`bercon_player_ping_seconds_bucket{server="local",le="0.05"} 3`,
+		`bercon_player_ping_seconds_bucket{server="local",le="+Inf"} 4`,
+		`bercon_player_ping_seconds_count{server="local"} 4`,
+		`bercon_bans{server="local",type="guid",duration="permanent"} 1`,
+		`bercon_bans{server="local",type="guid",duration="temporary"} 1`,
+		`bercon_bans{server="local",type="ip",duration="permanent"} 1`,
+		`bercon_admins{server="local"} 2`,
+		`bercon_rtt_seconds{server="local",stat="avg"}`,
+		`bercon_last_success_timestamp_seconds{server="local"}`,
+	} {
+		if !strings.Contains(body, want+"\n") && !strings.Contains(body, want+" ") {
+			t.Errorf("missing %q", want)
+		}
+	}
+
+	if strings.Contains(body, `bercon_players_online{server="down"}`) {
+		t.Error("down server reports players")
+	}
+	if t.Failed() {
+		t.Log(body)
+	}
+
+	// the connection is kept between polls
+	e.Poll(context.Background())
+	if ok, _ := srv.Logins(); ok != 1 {
+		t.Fatalf("logins = %d, want 1", ok)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,209 @@
+package exporter
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pingBuckets are the upper bounds of the player ping histogram in seconds.
+var pingBuckets = []float64{0.025, 0.05, 0.1, 0.15, 0.2, 0.3, 0.5, 1}
+
+// ServeHTTP writes the metrics of the last poll of every target.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = e.WriteMetrics(w)
+}
+
+// WriteMetrics writes the metrics of the last poll of every target in the
+// Prometheus text exposition format. Targets that are down only report
+// bercon_up, the poll duration and the last success time.
+func (e *Exporter) WriteMetrics(w io.Writer) error {
+	type server struct {
+		name string
+		snap snapshot
+	}
+
+	servers := make([]server, 0, len(e.targets))
+	for _, t := range e.targets {
+		servers = append(servers, server{name: t.Name, snap: t.snapshot()})
+	}
+
+	m := &metricWriter{w: bufio.NewWriter(w)}
+
+	m.family("bercon_up", "gauge", "Whether the last poll of the server succeeded.")
+	for _, s := range servers {
+		m.sample("bercon_up", s.name, boolValue(s.snap.up))
+	}
+
+	m.family("bercon_poll_duration_seconds", "gauge", "Duration of the last poll.")
+	for _, s := range servers {
+		if !s.snap.polledAt.IsZero() {
+			m.sample("bercon_poll_duration_seconds", s.name, s.snap.duration.Seconds())
+		}
+	}
+
+	m.family("bercon_last_success_timestamp_seconds", "gauge", "Unix time of the last successful poll.")
+	for _, s := range servers {
+		if !s.snap.lastSuccess.IsZero() {
+			m.sample("bercon_last_success_timestamp_seconds", s.name, unixSeconds(s.snap.lastSuccess))
+		}
+	}
+
+	up := servers[:0:0]
+	for _, s := range servers {
+		if s.snap.up {
+			up = append(up, s)
+		}
+	}
+
+	m.family("bercon_players_online", "gauge", "Players connected to the server.")
+	for _, s := range up {
+		m.sample("bercon_players_online", s.name, float64(len(s.snap.players)))
+	}
+
+	m.family("bercon_players", "gauge", "Players connected to the server by state.")
+	for _, s := range up {
+		var lobby int
+		for _, p := range s.snap.players {
+			if p.Lobby {
+				lobby++
+			}
+		}
+		m.sample("bercon_players", s.name, float64(lobby), "state", "lobby")
+		m.sample("bercon_players", s.name, float64(len(s.snap.players)-lobby), "state", "ingame")
+	}
+
+	m.family("bercon_players_invalid_guid", "gauge", "Players whose GUID is not verified by BattlEye.")
+	for _, s := range up {
+		var invalid int
+		for _, p := range s.snap.players {
+			if !p.Valid {
+				invalid++
+			}
+		}
+		m.sample("bercon_players_invalid_guid", s.name, float64(invalid))
+	}
+
+	m.family("bercon_player_ping_seconds", "histogram", "Ping of the connected players at the last poll.")
+	for _, s := range up {
+		counts := make([]int, len(pingBuckets))
+		var sum float64
+		for _, p := range s.snap.players {
+			ping := float64(p.Ping) / 1000
+			sum += ping
+			for i, le := range pingBuckets {
+				if ping <= le {
+					counts[i]++
+				}
+			}
+		}
+		for i, le := range pingBuckets {
+			m.sample("bercon_player_ping_seconds_bucket", s.name, float64(counts[i]),
+				"le", strconv.FormatFloat(le, 'g', -1, 64))
+		}
+		m.sample("bercon_player_ping_seconds_bucket", s.name, float64(len(s.snap.players)), "le", "+Inf")
+		m.sample("bercon_player_ping_seconds_sum", s.name, sum)
+		m.sample("bercon_player_ping_seconds_count", s.name, float64(len(s.snap.players)))
+	}
+
+	m.family("bercon_bans", "gauge", "Bans in the server ban list by type and duration.")
+	for _, s := range up {
+		var guidPerm, ipPerm int
+		for _, b := range s.snap.bans.GUIDBans {
+			if b.MinutesLeft < 0 {
+				guidPerm++
+			}
+		}
+		for _, b := range s.snap.bans.IPBans {
+			if b.MinutesLeft < 0 {
+				ipPerm++
+			}
+		}
+		m.sample("bercon_bans", s.name, float64(guidPerm), "type", "guid", "duration", "permanent")
+		m.sample("bercon_bans", s.name, float64(len(s.snap.bans.GUIDBans)-guidPerm), "type", "guid", "duration", "temporary")
+		m.sample("bercon_bans", s.name, float64(ipPerm), "type", "ip", "duration", "permanent")
+		m.sample("bercon_bans", s.name, float64(len(s.snap.bans.IPBans)-ipPerm), "type", "ip", "duration", "temporary")
+	}
+
+	m.family("bercon_admins", "gauge", "RCon admins connected to the server.")
+	for _, s := range up {
+		m.sample("bercon_admins", s.name, float64(len(s.snap.admins)))
+	}
+
+	m.family("bercon_rtt_seconds", "gauge", "RCON command round trip time of the exporter connection.")
+	for _, s := range up {
+		m.sample("bercon_rtt_seconds", s.name, s.snap.stats.LastRTT.Seconds(), "stat", "last")
+		m.sample("bercon_rtt_seconds", s.name, s.snap.stats.AvgRTT.Seconds(), "stat", "avg")
+		m.sample("bercon_rtt_seconds", s.name, s.snap.stats.P95RTT.Seconds(), "stat", "p95")
+	}
+
+	return m.flush()
+}
+
+// metricWriter writes text exposition lines, keeping the first error.
+type metricWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (m *metricWriter) family(name, typ, help string) {
+	m.write("# HELP ", name, " ", help, "\n# TYPE ", name, " ", typ, "\n")
+}
+
+// sample writes a series with the server label and extra label pairs.
+func (m *metricWriter) sample(name, server string, v float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteString(`{server="`)
+	b.WriteString(escapeLabel(server))
+	b.WriteByte('"')
+	for i := 0; i+1 < len(labels); i += 2 {
+		b.WriteByte(',')
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteString("} ")
+	b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	b.WriteByte('\n')
+
+	m.write(b.String())
+}
+
+func (m *metricWriter) write(parts ...string) {
+	for _, p := range parts {
+		if m.err != nil {
+			return
+		}
+		_, m.err = m.w.WriteString(p)
+	}
+}
+
+func (m *metricWriter) flush() error {
+	if m.err != nil {
+		return m.err
+	}
+	return m.w.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,204 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+const (
+	// DefaultReplaySize is the number of recent events kept per profile
+	// for replay to new stream clients.
+	DefaultReplaySize = 100
+
+	// clientBufferSize is the event buffer of a stream client; a client
+	// that falls further behind misses events.
+	clientBufferSize = 64
+
+	// heartbeatInterval is the period of SSE comments that keep idle
+	// streams open through proxies.
+	heartbeatInterval = 30 * time.Second
+)
+
+// Event is a server message sent by the events endpoint. Seq increases by
+// one per message of a profile and is also used as the SSE event id.
+type Event struct {
+	Time time.Time `json:"time"`
+	beparser.Event
+	Seq uint64 `json:"seq"`
+}
+
+// hub fans the messages of a profile out to stream clients and keeps the
+// most recent ones for replay. It outlives connections of the profile.
+type hub struct {
+	clients map[chan Event]struct{}
+	ring    []Event // most recent events, oldest first
+	size    int
+	next    uint64
+	mu      sync.Mutex
+}
+
+func newHub(size int) *hub {
+	return &hub{clients: make(map[chan Event]struct{}), size: size}
+}
+
+// setSize changes the replay size, dropping the oldest events if needed.
+func (h *hub) setSize(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.size = n
+	h.trim()
+}
+
+// pump publishes events of a connection subscription until it is closed.
+func (h *hub) pump(sub *bercon.Subscription) {
+	for pe := range sub.C {
+		h.publish(pe.Time, beparser.ParseEvent(pe.Data))
+	}
+}
+
+func (h *hub) publish(t time.Time, ev beparser.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.next++
+	e := Event{Seq: h.next, Time: t, Event: ev}
+
+	h.ring = append(h.ring, e)
+	h.trim()
+
+	for ch := range h.clients {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// subscribe registers a client and returns up to replay recent events.
+// Both happen atomically, so the client sees no gap and no duplicate.
+func (h *hub) subscribe(replay int) (chan Event, []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, clientBufferSize)
+	h.clients[ch] = struct{}{}
+
+	replay = min(replay, len(h.ring))
+	return ch, slices.Clone(h.ring[len(h.ring)-replay:])
+}
+
+func (h *hub) unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients, ch)
+}
+
+// trim drops events above the replay size.
+// NOTE: must be called with mu held.
+func (h *hub) trim() {
+	if over := len(h.ring) - h.size; over > 0 {
+		h.ring = append(h.ring[:0], h.ring[over:]...)
+	}
+}
+
+// handleEvents streams server messages of a profile as Server-Sent Events.
+// Query parameters: type (repeatable or comma separated) limits event
+// types, replay sends up to that many recent events first.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("profile")
+	query := r.URL.Query()
+
+	var types []string
+	for _, v := range query["type"] {
+		for t := range strings.SplitSeq(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+	}
+
+	var replay int
+	if v := query.Get("replay"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "replay must be a non-negative number")
+			return
+		}
+		replay = n
+	}
+
+	if err := s.authorize(r, name, ""); err != nil {
+		writeError(w, statusFor(err), err.Error())
+		return
+	}
+
+	if _, err := s.connection(name); err != nil {
+		writeError(w, statusFor(err), err.Error())
+		return
+	}
+	h := s.profiles[name].hub
+
+	ch, past := h.subscribe(replay)
+	defer h.unsubscribe(ch)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(e Event) error {
+		if len(types) > 0 && !slices.Contains(types, string(e.Type)) {
+			return nil
+		}
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.Seq, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for _, e := range past {
+		if send(e) != nil {
+			return
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case e := <-ch:
+			if send(e) != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
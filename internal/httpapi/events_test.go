@@ -0,0 +1,127 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+)
+
+// api returns the Server behind a test server.
+func api(t *testing.T, ts *httptest.Server) *Server {
+	t.Helper()
+	return ts.Config.Handler.(*Server)
+}
+
+// readEvents reads n SSE data events from a stream.
+func readEvents(t *testing.T, sc *bufio.Scanner, n int) []Event {
+	t.Helper()
+
+	var out []Event
+	for len(out) < n && sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatalf("bad event %q: %v", data, err)
+		}
+		out = append(out, e)
+	}
+	if len(out) < n {
+		t.Fatalf("got %d events, want %d: %v", len(out), n, sc.Err())
+	}
+
+	return out
+}
+
+func TestEventsReplayAndFilter(t *testing.T) {
+	srv, ts := newTestServer(t)
+
+	// open the connection and collect history before any client
+	var players beparser.Players
+	getJSON(t, ts.URL+"/servers/local/players", &players)
+
+	srv.Push("(Global) Survivor: one")
+	srv.Push("Player #1 Bandit disconnected")
+	srv.Push("(Side) Bandit: two")
+	if !srv.WaitAcked(time.Second) {
+		t.Fatal("messages not acked")
+	}
+
+	// wait for the pump to publish them
+	h := api(t, ts).profiles["local"].hub
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		h.mu.Lock()
+		n := len(h.ring)
+		h.mu.Unlock()
+
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("published %d events, want 3", n)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET",
+		ts.URL+"/servers/local/events?type=chat&replay=2", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+
+	srv.Push("(Direct) Medic: three")
+
+	sc := bufio.NewScanner(resp.Body)
+	events := readEvents(t, sc, 2)
+
+	if events[0].Text != "two" || events[0].Channel != beparser.ChannelSide || events[0].ID != -1 {
+		t.Fatalf("replayed event = %+v", events[0])
+	}
+	if events[1].Text != "three" || events[1].Seq != 4 {
+		t.Fatalf("live event = %+v", events[1])
+	}
+}
+
+func TestEventsBadReplay(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	var body map[string]string
+	if code := getJSON(t, ts.URL+"/servers/local/events?replay=-1", &body); code != http.StatusBadRequest {
+		t.Fatalf("status %d", code)
+	}
+}
+
+func TestHubReplaySize(t *testing.T) {
+	h := newHub(2)
+	for _, text := range []string{"a", "b", "c"} {
+		h.publish(time.Now(), beparser.Event{Text: text})
+	}
+
+	ch, past := h.subscribe(10)
+	defer h.unsubscribe(ch)
+
+	if len(past) != 2 || past[0].Text != "b" || past[1].Seq != 3 {
+		t.Fatalf("past = %+v", past)
+	}
+}
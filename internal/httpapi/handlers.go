@@ -0,0 +1,139 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+// maxCommandBody limits the size of a command request body.
+const maxCommandBody = 4096
+
+// commandRequest is the body of POST /servers/{profile}/command.
+type commandRequest struct {
+	Command string `json:"command"`
+}
+
+// serverInfo is an entry of GET /servers.
+type serverInfo struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /servers", s.handleServers)
+	s.mux.HandleFunc("GET /servers/{profile}/players", s.handleQuery("players"))
+	s.mux.HandleFunc("GET /servers/{profile}/bans", s.handleQuery("bans"))
+	s.mux.HandleFunc("GET /servers/{profile}/admins", s.handleQuery("admins"))
+	s.mux.HandleFunc("POST /servers/{profile}/command", s.handleCommand)
+	s.mux.HandleFunc("GET /servers/{profile}/events", s.handleEvents)
+}
+
+// handleServers lists the profiles the caller may access.
+func (s *Server) handleServers(w http.ResponseWriter, r *http.Request) {
+	out := make([]serverInfo, 0, len(s.names))
+	for _, name := range s.names {
+		if err := s.authorize(r, name, ""); err != nil {
+			if errors.Is(err, ErrUnauthorized) {
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			continue
+		}
+		p := s.profiles[name]
+
+		state := "disconnected"
+		p.mu.Lock()
+		if p.conn != nil {
+			state = p.conn.State().String()
+		}
+		p.mu.Unlock()
+
+		out = append(out, serverInfo{Name: name, State: state})
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleQuery serves a fixed read-only command.
+func (s *Server) handleQuery(cmd string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.run(w, r, r.PathValue("profile"), cmd)
+	}
+}
+
+func (s *Server) handleCommand(w http.ResponseWriter, r *http.Request) {
+	var req commandRequest
+	body := http.MaxBytesReader(w, r.Body, maxCommandBody)
+	if err := json.NewDecoder(body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	req.Command = strings.TrimSpace(req.Command)
+	if req.Command == "" {
+		writeError(w, http.StatusBadRequest, "command must be provided")
+		return
+	}
+
+	s.run(w, r, r.PathValue("profile"), req.Command)
+}
+
+// run sends cmd and writes the parsed response.
+func (s *Server) run(w http.ResponseWriter, r *http.Request, name, cmd string) {
+	if err := s.authorize(r, name, cmd); err != nil {
+		writeError(w, statusFor(err), err.Error())
+		return
+	}
+
+	data, err := s.send(r.Context(), name, cmd)
+	if err != nil {
+		s.logger.Warn("command failed", "profile", name, "command", cmd, "err", err)
+		writeError(w, statusFor(err), err.Error())
+		return
+	}
+
+	parsed, err := beparser.ParseWithGeo(data, cmd, s.geo)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parsed)
+}
+
+// statusFor maps a send error to an HTTP status.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownProfile):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, bercon.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, bercon.ErrCommandTooLong):
+		return http.StatusBadRequest
+	case errors.Is(err, bercon.ErrBufferFull), errors.Is(err, bercon.ErrConnectionDown):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
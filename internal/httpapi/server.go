@@ -0,0 +1,228 @@
+/*
+Package httpapi exposes BattlEye RCON servers over an HTTP JSON API.
+
+Each profile keeps one long-lived bercon.Connection, opened on first use
+and reused by all requests. Endpoints:
+
+	GET  /servers                     profile names and connection states
+	GET  /servers/{profile}/players   beparser.Players
+	GET  /servers/{profile}/bans      beparser.Bans
+	GET  /servers/{profile}/admins    beparser.Admins
+	POST /servers/{profile}/command   {"command": "..."} -> parsed response
+	GET  /servers/{profile}/events    Server-Sent Events stream of messages
+
+Responses use the beparser JSON shapes, errors are {"error": "..."}.
+The events stream sends every server message as an Event in JSON and
+accepts ?type=chat,connected to filter by event type and ?replay=N to
+start with up to N recent messages.
+*/
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+var (
+	// ErrUnknownProfile is returned for requests to a profile that is not served.
+	ErrUnknownProfile = errors.New("unknown profile")
+
+	// ErrUnauthorized is wrapped by an Authorizer for missing or unknown
+	// credentials (HTTP 401).
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden is wrapped by an Authorizer for requests the caller is
+	// not allowed to make (HTTP 403).
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Authorizer decides whether a request may run command on profile. The
+// command is empty for the events stream, which only needs profile access.
+// Returned errors should wrap ErrUnauthorized or ErrForbidden.
+type Authorizer func(r *http.Request, profile, command string) error
+
+// Profile is a server exposed by the API.
+type Profile struct {
+	Name     string          // path segment and label, usually the rc profile name
+	Addr     string          // host:port of the RCON endpoint
+	Password string          // RCON password
+	Options  []bercon.Option // applied when the connection is (re)opened
+	Timeout  time.Duration   // per-command timeout; the connection deadline when zero
+}
+
+// Server is an http.Handler serving the API for a set of profiles.
+type Server struct {
+	auth     Authorizer
+	geo      *geoip2.Reader
+	logger   *slog.Logger
+	profiles map[string]*profile
+	mux      *http.ServeMux
+	names    []string
+}
+
+// profile is a served server with its lazily opened connection. mu
+// guards conn; dial is held while opening it, so requests waiting for the
+// connection do not block readers of conn such as GET /servers.
+type profile struct {
+	conn *bercon.Connection
+	hub  *hub
+	Profile
+	mu   sync.Mutex
+	dial sync.Mutex
+}
+
+// New returns an API server for profiles. Connections are opened on the
+// first request to each profile.
+func New(profiles []Profile) *Server {
+	s := &Server{
+		profiles: make(map[string]*profile, len(profiles)),
+		logger:   slog.New(slog.DiscardHandler),
+		mux:      http.NewServeMux(),
+	}
+
+	for _, p := range profiles {
+		s.profiles[p.Name] = &profile{Profile: p, hub: newHub(DefaultReplaySize)}
+		s.names = append(s.names, p.Name)
+	}
+
+	s.routes()
+	return s
+}
+
+// SetLogger sets the logger for requests and connection failures. nil
+// disables logging.
+func (s *Server) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	s.logger = l
+}
+
+// SetReplaySize sets how many recent events per profile are kept for
+// replay; n < 0 keeps DefaultReplaySize. Call it before serving requests.
+func (s *Server) SetReplaySize(n int) {
+	if n < 0 {
+		n = DefaultReplaySize
+	}
+
+	for _, p := range s.profiles {
+		p.hub.setSize(n)
+	}
+}
+
+// SetAuthorizer sets the check applied to every request before its
+// command is sent. nil allows everything. Call it before serving requests.
+func (s *Server) SetAuthorizer(fn Authorizer) {
+	s.auth = fn
+}
+
+// authorize applies the Authorizer, if any.
+func (s *Server) authorize(r *http.Request, profile, command string) error {
+	if s.auth == nil {
+		return nil
+	}
+
+	return s.auth(r, profile, command)
+}
+
+// SetGeoReader enables geolocation of players, bans and admins.
+// Call it before serving requests.
+func (s *Server) SetGeoReader(r *geoip2.Reader) {
+	s.geo = r
+}
+
+// ServeHTTP dispatches API requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close closes all open connections.
+func (s *Server) Close() error {
+	var errs []error
+	for _, p := range s.profiles {
+		p.dial.Lock()
+		p.mu.Lock()
+		if p.conn != nil {
+			errs = append(errs, p.conn.Close())
+			p.conn = nil
+		}
+		p.mu.Unlock()
+		p.dial.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// connection returns the open connection of a profile, opening it when
+// there is none or the previous one stopped for good.
+func (s *Server) connection(name string) (*bercon.Connection, error) {
+	p, ok := s.profiles[name]
+	if !ok {
+		return nil, ErrUnknownProfile
+	}
+
+	if conn := p.current(); conn != nil {
+		return conn, nil
+	}
+
+	p.dial.Lock()
+	defer p.dial.Unlock()
+
+	// opened by another request while waiting
+	if conn := p.current(); conn != nil {
+		return conn, nil
+	}
+
+	conn, err := bercon.OpenWithOptions(p.Addr, p.Password, p.Options...)
+	if err != nil {
+		s.logger.Warn("connection failed", "profile", name, "addr", p.Addr, "err", err)
+		return nil, err
+	}
+	s.logger.Info("connected", "profile", name, "addr", p.Addr)
+
+	p.mu.Lock()
+	p.conn = conn
+	p.mu.Unlock()
+
+	sub := conn.Subscribe(bercon.IsMessage, bercon.DefaultMessagesBufferSize, bercon.DropOldest)
+	go p.hub.pump(sub)
+
+	return conn, nil
+}
+
+// current returns the open connection, or nil after dropping one that
+// stopped for good.
+func (p *profile) current() *bercon.Connection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil && p.conn.State() == bercon.StateClosed {
+		_ = p.conn.Close()
+		p.conn = nil
+	}
+
+	return p.conn
+}
+
+// send runs a command on a profile connection.
+func (s *Server) send(ctx context.Context, name, cmd string) ([]byte, error) {
+	conn, err := s.connection(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if t := s.profiles[name].Timeout; t > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t)
+		defer cancel()
+	}
+
+	return conn.SendContext(ctx, cmd)
+}
@@ -0,0 +1,213 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+	"github.com/woozymasta/bercon-cli/pkg/bercon/bercontest"
+)
+
+func newTestServer(t *testing.T) (*bercontest.Server, *httptest.Server) {
+	t.Helper()
+
+	srv := bercontest.NewServer("pw")
+	t.Cleanup(func() { _ = srv.Close() })
+
+	api := New([]Profile{{
+		Name:     "local",
+		Addr:     srv.Addr,
+		Password: "pw",
+		Options:  []bercon.Option{bercon.WithDeadline(time.Second)},
+	}})
+	t.Cleanup(func() { _ = api.Close() })
+
+	ts := httptest.NewServer(api)
+	t.Cleanup(ts.Close)
+
+	return srv, ts
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+
+	resp, err := http.Get(url) // #nosec G107 -- test server URL
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestQueries(t *testing.T) {
+	srv, ts := newTestServer(t)
+
+	var players beparser.Players
+	if code := getJSON(t, ts.URL+"/servers/local/players", &players); code != http.StatusOK {
+		t.Fatalf("players: status %d", code)
+	}
+	if len(players) != 4 || players[0].Name != "Survivor" {
+		t.Fatalf("players = %+v", players)
+	}
+
+	var bans beparser.Bans
+	getJSON(t, ts.URL+"/servers/local/bans", &bans)
+	if len(bans.GUIDBans) != 2 || len(bans.IPBans) != 2 {
+		t.Fatalf("bans = %+v", bans)
+	}
+
+	var admins beparser.Admins
+	getJSON(t, ts.URL+"/servers/local/admins", &admins)
+	if len(admins) != 2 {
+		t.Fatalf("admins = %+v", admins)
+	}
+
+	var servers []serverInfo
+	getJSON(t, ts.URL+"/servers", &servers)
+	if len(servers) != 1 || servers[0].Name != "local" || servers[0].State != "logged-in" {
+		t.Fatalf("servers = %+v", servers)
+	}
+
+	if ok, _ := srv.Logins(); ok != 1 {
+		t.Fatalf("logins = %d, want a single reused connection", ok)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	srv, ts := newTestServer(t)
+	srv.Respond("say -1 hello", "")
+
+	resp, err := http.Post(ts.URL+"/servers/local/command", "application/json",
+		strings.NewReader(`{"command": "say -1 hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	cmds := srv.Commands()
+	if len(cmds) != 1 || cmds[0] != "say -1 hello" {
+		t.Fatalf("commands = %q", cmds)
+	}
+
+	resp, err = http.Post(ts.URL+"/servers/local/command", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("empty command: status %d", resp.StatusCode)
+	}
+}
+
+func TestUnknownProfile(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	var body map[string]string
+	if code := getJSON(t, ts.URL+"/servers/nope/players", &body); code != http.StatusNotFound {
+		t.Fatalf("status %d", code)
+	}
+	if body["error"] == "" {
+		t.Fatalf("body = %v", body)
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	srv := bercontest.NewServer("pw")
+	defer func() { _ = srv.Close() }()
+
+	api := New([]Profile{{Name: "local", Addr: srv.Addr, Password: "pw"}})
+	defer func() { _ = api.Close() }()
+	api.SetAuthorizer(func(r *http.Request, _, command string) error {
+		if r.Header.Get("Authorization") == "" {
+			return ErrUnauthorized
+		}
+		if strings.HasPrefix(command, "#") {
+			return ErrForbidden
+		}
+		return nil
+	})
+
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	do := func(auth, body string) int {
+		req, _ := http.NewRequest("POST", ts.URL+"/servers/local/command", strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := do("", `{"command": "players"}`); code != http.StatusUnauthorized {
+		t.Fatalf("no token: status %d", code)
+	}
+	if code := do("Bearer x", `{"command": "#shutdown"}`); code != http.StatusForbidden {
+		t.Fatalf("denied: status %d", code)
+	}
+	if code := do("Bearer x", `{"command": "players"}`); code != http.StatusOK {
+		t.Fatalf("allowed: status %d", code)
+	}
+
+	if cmds := srv.Commands(); len(cmds) != 1 || cmds[0] != "players" {
+		t.Fatalf("sent commands = %q", cmds)
+	}
+}
+
+func TestListWhileDialing(t *testing.T) {
+	srv := bercontest.NewServer("pw")
+	defer func() { _ = srv.Close() }()
+	srv.SetDrop(func(_ bercontest.Direction, p bercon.Packet) bool {
+		return p.Kind == bercon.LoginPacket
+	})
+
+	api := New([]Profile{{
+		Name:     "silent",
+		Addr:     srv.Addr,
+		Password: "pw",
+		Options:  []bercon.Option{bercon.WithDeadline(2 * time.Second)},
+	}})
+	defer func() { _ = api.Close() }()
+
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if resp, err := http.Get(ts.URL + "/servers/silent/players"); err == nil { // #nosec G107 -- test server URL
+			_ = resp.Body.Close()
+		}
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	var servers []serverInfo
+	if code := getJSON(t, ts.URL+"/servers", &servers); code != http.StatusOK {
+		t.Fatalf("servers: status %d", code)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("servers took %s while a login was pending", d)
+	}
+	if len(servers) != 1 || servers[0].State != "disconnected" {
+		t.Fatalf("servers = %+v", servers)
+	}
+
+	<-done
+}
@@ -0,0 +1,94 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetHistorySize limits the number of kept history entries; n <= 0 keeps
+// DefaultHistorySize.
+func (e *Editor) SetHistorySize(n int) {
+	if n <= 0 {
+		n = DefaultHistorySize
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.limit = n
+	e.trimHistory()
+}
+
+// AddHistory appends line to history. Blank lines and repeats of the last
+// entry are skipped.
+func (e *Editor) AddHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+
+	e.history = append(e.history, line)
+	e.trimHistory()
+}
+
+// History returns a copy of the history, oldest first.
+func (e *Editor) History() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.history...)
+}
+
+// LoadHistory appends entries from a file with one line per entry.
+// A missing file is not an error.
+func (e *Editor) LoadHistory(path string) error {
+	f, err := os.Open(path) // #nosec G304 -- history path is chosen by the user
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		e.AddHistory(sc.Text())
+	}
+
+	return sc.Err()
+}
+
+// SaveHistory writes the history to path, creating its directory with
+// owner-only permissions, since commands may contain player data.
+func (e *Editor) SaveHistory(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, line := range e.History() {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0o600)
+}
+
+// trimHistory drops the oldest entries above the limit.
+// NOTE: must be called with mu held.
+func (e *Editor) trimHistory() {
+	if over := len(e.history) - e.limit; over > 0 {
+		e.history = append(e.history[:0], e.history[over:]...)
+	}
+}
@@ -0,0 +1,85 @@
+package lineedit
+
+// Special keys decoded from terminal escape sequences.
+const (
+	keyUnknown rune = -(iota + 1)
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+// readKey reads a single key press, decoding CSI (ESC [) and SS3 (ESC O)
+// sequences for arrows, Home, End and Delete.
+func (e *Editor) readKey() (rune, error) {
+	r, _, err := e.reader.ReadRune()
+	if err != nil || r != 0x1b {
+		return r, err
+	}
+
+	r, _, err = e.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	switch r {
+	case 'O':
+		r, _, err = e.reader.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		return finalKey(r), nil
+
+	case '[':
+		var param rune
+		for {
+			r, _, err = e.reader.ReadRune()
+			if err != nil {
+				return 0, err
+			}
+			if r >= 0x40 && r <= 0x7e {
+				break
+			}
+			if param == 0 && r >= '0' && r <= '9' {
+				param = r
+			}
+		}
+
+		if r != '~' {
+			return finalKey(r), nil
+		}
+
+		switch param {
+		case '1', '7':
+			return keyHome, nil
+		case '4', '8':
+			return keyEnd, nil
+		case '3':
+			return keyDelete, nil
+		}
+	}
+
+	return keyUnknown, nil
+}
+
+func finalKey(r rune) rune {
+	switch r {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		return keyRight
+	case 'D':
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	default:
+		return keyUnknown
+	}
+}
@@ -0,0 +1,383 @@
+/*
+Package lineedit is a minimal interactive line editor for terminals.
+
+It supports Emacs-style cursor movement and editing keys, arrow keys,
+in-memory history with optional persistence to a file, tab completion of
+the word before the cursor and printing asynchronous output above the
+prompt without corrupting the line being edited. When the input is not a
+terminal, lines are read as is, without prompt or editing.
+*/
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultHistorySize is the number of history entries kept by New.
+const DefaultHistorySize = 1000
+
+// ErrInterrupt is returned by ReadLine when Ctrl+C is pressed.
+var ErrInterrupt = errors.New("interrupted")
+
+// Candidate is a tab completion for the word before the cursor.
+type Candidate struct {
+	Text    string // replacement for the word
+	Display string // shown in the candidates list; Text when empty
+}
+
+// Completer returns completion candidates for the last word of head, the
+// line text before the cursor.
+type Completer func(head string) []Candidate
+
+// Editor reads lines from a terminal. ReadLine must not be called
+// concurrently; Write may be called from any goroutine.
+type Editor struct {
+	// Completer is called on Tab; nil disables completion.
+	Completer Completer
+
+	in      *os.File
+	out     io.Writer
+	reader  *bufio.Reader
+	prompt  string
+	history []string
+	line    []rune
+	draft   []rune // line being edited before browsing history
+	width   func() int
+	mu      sync.Mutex
+	pos     int
+	hpos    int
+	limit   int
+	active  bool
+	tty     bool
+}
+
+// New returns an editor reading from in and echoing to out. Line editing is
+// enabled only when both are terminals.
+func New(in, out *os.File) *Editor {
+	return &Editor{
+		in:     in,
+		out:    out,
+		reader: bufio.NewReader(in),
+		width:  func() int { return termWidth(out.Fd()) },
+		limit:  DefaultHistorySize,
+		tty:    isTerminal(in.Fd()) && isTerminal(out.Fd()),
+	}
+}
+
+// IsTerminal reports whether line editing is enabled.
+func (e *Editor) IsTerminal() bool {
+	return e.tty
+}
+
+// ReadLine prints prompt and returns the next line without the trailing
+// newline. It returns io.EOF on Ctrl+D at an empty line or at the end of
+// input, and ErrInterrupt on Ctrl+C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if !e.tty {
+		line, err := e.reader.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return "", err
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	restore, err := makeRaw(e.in.Fd())
+	if err != nil {
+		return "", fmt.Errorf("terminal: %w", err)
+	}
+	defer func() { _ = restore() }()
+
+	return e.edit(prompt)
+}
+
+// Write prints p above the prompt while a line is being edited, or as is
+// otherwise. Output is expected to end with a newline.
+func (e *Editor) Write(p []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.active {
+		return e.out.Write(p)
+	}
+
+	if _, err := io.WriteString(e.out, "\r\x1b[K"); err != nil {
+		return 0, err
+	}
+	n, err := e.out.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if len(p) > 0 && p[len(p)-1] != '\n' {
+		_, _ = io.WriteString(e.out, "\n")
+	}
+
+	e.refresh()
+	return n, nil
+}
+
+// edit runs the editing loop on a terminal already in raw mode.
+func (e *Editor) edit(prompt string) (string, error) {
+	e.mu.Lock()
+	e.prompt = prompt
+	e.line = e.line[:0]
+	e.draft = nil
+	e.pos = 0
+	e.hpos = len(e.history)
+	e.active = true
+	e.refresh()
+	e.mu.Unlock()
+
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			e.finish("\n")
+			return "", err
+		}
+
+		e.mu.Lock()
+		line, done, err := e.handle(k)
+		e.mu.Unlock()
+
+		if done {
+			return line, err
+		}
+	}
+}
+
+// handle applies a single key to the line.
+// NOTE: must be called with mu held.
+func (e *Editor) handle(k rune) (string, bool, error) {
+	switch k {
+	case '\r', '\n':
+		line := string(e.line)
+		e.finishLocked("\n")
+		return line, true, nil
+
+	case ctrl('C'):
+		e.finishLocked("^C\n")
+		return "", true, ErrInterrupt
+
+	case ctrl('D'):
+		if len(e.line) == 0 {
+			e.finishLocked("\n")
+			return "", true, io.EOF
+		}
+		e.deleteAt(e.pos)
+
+	case keyDelete:
+		e.deleteAt(e.pos)
+
+	case 127, ctrl('H'):
+		if e.pos > 0 {
+			e.pos--
+			e.deleteAt(e.pos)
+		}
+
+	case ctrl('A'), keyHome:
+		e.pos = 0
+
+	case ctrl('E'), keyEnd:
+		e.pos = len(e.line)
+
+	case ctrl('B'), keyLeft:
+		e.pos = max(e.pos-1, 0)
+
+	case ctrl('F'), keyRight:
+		e.pos = min(e.pos+1, len(e.line))
+
+	case ctrl('K'):
+		e.line = e.line[:e.pos]
+
+	case ctrl('U'):
+		e.line = append(e.line[:0], e.line[e.pos:]...)
+		e.pos = 0
+
+	case ctrl('W'):
+		start := e.pos
+		for start > 0 && e.line[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && e.line[start-1] != ' ' {
+			start--
+		}
+		e.line = append(e.line[:start], e.line[e.pos:]...)
+		e.pos = start
+
+	case ctrl('L'):
+		_, _ = io.WriteString(e.out, "\x1b[H\x1b[2J")
+
+	case ctrl('P'), keyUp:
+		e.browse(-1)
+
+	case ctrl('N'), keyDown:
+		e.browse(1)
+
+	case '\t':
+		e.complete()
+
+	default:
+		if k < ' ' {
+			return "", false, nil
+		}
+
+		e.line = append(e.line, 0)
+		copy(e.line[e.pos+1:], e.line[e.pos:])
+		e.line[e.pos] = k
+		e.pos++
+	}
+
+	e.refresh()
+	return "", false, nil
+}
+
+func (e *Editor) deleteAt(i int) {
+	if i < len(e.line) {
+		e.line = append(e.line[:i], e.line[i+1:]...)
+	}
+}
+
+// browse moves through history by step, keeping the unsent line as draft.
+func (e *Editor) browse(step int) {
+	next := e.hpos + step
+	if next < 0 || next > len(e.history) {
+		e.bell()
+		return
+	}
+
+	if e.hpos == len(e.history) {
+		e.draft = append(e.draft[:0], e.line...)
+	}
+
+	e.hpos = next
+	if next == len(e.history) {
+		e.line = append(e.line[:0], e.draft...)
+	} else {
+		e.line = append(e.line[:0], []rune(e.history[next])...)
+	}
+	e.pos = len(e.line)
+}
+
+// complete replaces the word before the cursor with the single candidate
+// or the longest common prefix, or lists the candidates above the prompt.
+func (e *Editor) complete() {
+	if e.Completer == nil {
+		e.bell()
+		return
+	}
+
+	start := e.pos
+	for start > 0 && e.line[start-1] != ' ' {
+		start--
+	}
+	word := string(e.line[start:e.pos])
+
+	cands := e.Completer(string(e.line[:e.pos]))
+	switch len(cands) {
+	case 0:
+		e.bell()
+		return
+
+	case 1:
+		e.replace(start, cands[0].Text+" ")
+		return
+	}
+
+	prefix := cands[0].Text
+	for _, c := range cands[1:] {
+		prefix = commonPrefix(prefix, c.Text)
+	}
+	if len(prefix) > len(word) && strings.EqualFold(prefix[:len(word)], word) {
+		e.replace(start, prefix)
+		return
+	}
+
+	list := make([]string, 0, len(cands))
+	for _, c := range cands {
+		if c.Display != "" {
+			list = append(list, c.Display)
+		} else {
+			list = append(list, c.Text)
+		}
+	}
+	_, _ = fmt.Fprintf(e.out, "\r\x1b[K%s\n", strings.Join(list, "  "))
+}
+
+// replace substitutes the line from start up to the cursor with text.
+func (e *Editor) replace(start int, text string) {
+	tail := append([]rune(text), e.line[e.pos:]...)
+	e.line = append(e.line[:start], tail...)
+	e.pos = start + utf8.RuneCountInString(text)
+}
+
+// refresh redraws the prompt and the visible part of the line.
+// NOTE: must be called with mu held.
+func (e *Editor) refresh() {
+	width := 80
+	if e.width != nil {
+		if w := e.width(); w > 0 {
+			width = w
+		}
+	}
+
+	plen := utf8.RuneCountInString(e.prompt)
+	avail := max(width-plen-1, 1)
+
+	start := 0
+	if e.pos > avail {
+		start = e.pos - avail
+	}
+	end := min(len(e.line), start+avail)
+
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(e.prompt)
+	b.WriteString(string(e.line[start:end]))
+	b.WriteString("\x1b[K\r")
+	if n := plen + e.pos - start; n > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", n)
+	}
+
+	_, _ = io.WriteString(e.out, b.String())
+}
+
+func (e *Editor) finish(tail string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.finishLocked(tail)
+}
+
+func (e *Editor) finishLocked(tail string) {
+	e.pos = len(e.line)
+	e.refresh()
+	_, _ = io.WriteString(e.out, tail)
+	e.active = false
+}
+
+func (e *Editor) bell() {
+	_, _ = io.WriteString(e.out, "\a")
+}
+
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	for n > 0 && !utf8.ValidString(a[:n]) {
+		n--
+	}
+
+	return a[:n]
+}
+
+func ctrl(c rune) rune {
+	return c & 0x1f
+}
@@ -0,0 +1,185 @@
+package lineedit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string) (*Editor, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &Editor{
+		out:    out,
+		reader: bufio.NewReader(strings.NewReader(input)),
+		limit:  DefaultHistorySize,
+		tty:    true,
+	}, out
+}
+
+func TestEditKeys(t *testing.T) {
+	cases := []struct {
+		name, input, want string
+	}{
+		{"plain", "players\r", "players"},
+		{"backspace", "playerz\x7fs\r", "players"},
+		{"arrows", "plyers\x1b[D\x1b[D\x1b[D\x1b[Da\r", "players"},
+		{"home end", "ayers\x1bOHpl\x1b[F!\r", "players!"},
+		{"delete", "pXlayers\x01\x1b[C\x1b[3~\r", "players"},
+		{"kill", "say -1 hello\x17\x17bye\r", "say bye"},
+		{"kill line", "junk\x15players\r", "players"},
+		{"kill end", "players junk\x02\x02\x02\x02\x02\x0b\r", "players"},
+	}
+
+	for _, c := range cases {
+		e, _ := newTestEditor(c.input)
+		got, err := e.edit("> ")
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEditInterruptAndEOF(t *testing.T) {
+	e, _ := newTestEditor("abc\x03")
+	if _, err := e.edit("> "); !errors.Is(err, ErrInterrupt) {
+		t.Fatalf("Ctrl+C: got %v", err)
+	}
+
+	e, _ = newTestEditor("\x04")
+	if _, err := e.edit("> "); !errors.Is(err, io.EOF) {
+		t.Fatalf("Ctrl+D: got %v", err)
+	}
+
+	e, _ = newTestEditor("ab")
+	if _, err := e.edit("> "); !errors.Is(err, io.EOF) {
+		t.Fatalf("end of input: got %v", err)
+	}
+}
+
+func TestEditHistory(t *testing.T) {
+	e, _ := newTestEditor("\x1b[A\x1b[A\r" + "draft\x1b[A\x1b[B\r")
+	e.AddHistory("players")
+	e.AddHistory("bans")
+	e.AddHistory("bans")
+	e.AddHistory("  ")
+
+	if h := e.History(); len(h) != 2 {
+		t.Fatalf("history = %q", h)
+	}
+
+	if got, _ := e.edit("> "); got != "players" {
+		t.Fatalf("up twice: got %q", got)
+	}
+	if got, _ := e.edit("> "); got != "draft" {
+		t.Fatalf("up and down: got %q", got)
+	}
+}
+
+func TestEditComplete(t *testing.T) {
+	words := []string{"loadBans", "loadEvents", "loadScripts", "players"}
+	completer := func(head string) []Candidate {
+		word := head[strings.LastIndexByte(head, ' ')+1:]
+		var out []Candidate
+		for _, w := range words {
+			if strings.HasPrefix(strings.ToLower(w), strings.ToLower(word)) {
+				out = append(out, Candidate{Text: w})
+			}
+		}
+		return out
+	}
+
+	e, _ := newTestEditor("pl\t\r")
+	e.Completer = completer
+	if got, _ := e.edit("> "); got != "players " {
+		t.Fatalf("single: got %q", got)
+	}
+
+	e, out := newTestEditor("lo\tB\t\r")
+	e.Completer = completer
+	if got, _ := e.edit("> "); got != "loadBans " {
+		t.Fatalf("prefix: got %q", got)
+	}
+
+	e, out = newTestEditor("load\t\r")
+	e.Completer = completer
+	if got, _ := e.edit("> "); got != "load" {
+		t.Fatalf("ambiguous: got %q", got)
+	}
+	if !strings.Contains(out.String(), "loadBans  loadEvents  loadScripts\n") {
+		t.Fatalf("candidates not listed: %q", out.String())
+	}
+}
+
+func TestWriteAbovePrompt(t *testing.T) {
+	e, out := newTestEditor("")
+	e.active = true
+	e.prompt = "> "
+	e.line = []rune("pla")
+	e.pos = 3
+
+	if _, err := e.Write([]byte("event")); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "\r\x1b[Kevent\n\r> pla") {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestReadLineNoTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+
+	_, _ = w.WriteString("players\r\nbans")
+	_ = w.Close()
+
+	e := New(r, os.Stdout)
+	if e.IsTerminal() {
+		t.Fatal("pipe reported as terminal")
+	}
+
+	for _, want := range []string{"players", "bans"} {
+		got, err := e.ReadLine("> ")
+		if err != nil || got != want {
+			t.Fatalf("got %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := e.ReadLine("> "); !errors.Is(err, io.EOF) {
+		t.Fatalf("got %v, want EOF", err)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "history")
+
+	e, _ := newTestEditor("")
+	if err := e.LoadHistory(path); err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+
+	e.SetHistorySize(2)
+	for _, l := range []string{"players", "bans", "admins"} {
+		e.AddHistory(l)
+	}
+	if err := e.SaveHistory(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := newTestEditor("")
+	if err := loaded.LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	if h := loaded.History(); len(h) != 2 || h[0] != "bans" || h[1] != "admins" {
+		t.Fatalf("history = %q", h)
+	}
+}
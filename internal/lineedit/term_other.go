@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package lineedit
+
+import "errors"
+
+func isTerminal(uintptr) bool { return false }
+
+func makeRaw(uintptr) (func() error, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+func termWidth(uintptr) int { return 0 }
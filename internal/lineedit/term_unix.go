@@ -0,0 +1,45 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package lineedit
+
+import "golang.org/x/sys/unix"
+
+func isTerminal(fd uintptr) bool {
+	_, err := unix.IoctlGetTermios(int(fd), ioctlGetTermios) // #nosec G115 -- file descriptors fit in int
+	return err == nil
+}
+
+// makeRaw disables canonical mode, echo and signal keys on fd. Output
+// processing is kept, so "\n" still moves to the start of the next line.
+func makeRaw(fd uintptr) (func() error, error) {
+	ifd := int(fd) // #nosec G115 -- file descriptors fit in int
+
+	old, err := unix.IoctlGetTermios(ifd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *old
+	raw.Iflag &^= unix.BRKINT | unix.ICRNL | unix.INPCK | unix.ISTRIP | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ICANON | unix.IEXTEN | unix.ISIG
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	if err := unix.IoctlSetTermios(ifd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return unix.IoctlSetTermios(ifd, ioctlSetTermios, old)
+	}, nil
+}
+
+func termWidth(fd uintptr) int {
+	ws, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ) // #nosec G115 -- file descriptors fit in int
+	if err != nil {
+		return 0
+	}
+
+	return int(ws.Col)
+}
@@ -0,0 +1,45 @@
+package lineedit
+
+import "golang.org/x/sys/windows"
+
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
+
+// makeRaw disables line input, echo and Ctrl+C processing on the console
+// input fd and enables VT sequences for arrow keys. VT output processing
+// is enabled on stdout as well.
+func makeRaw(fd uintptr) (func() error, error) {
+	in := windows.Handle(fd)
+
+	var old uint32
+	if err := windows.GetConsoleMode(in, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old &^ (windows.ENABLE_ECHO_INPUT | windows.ENABLE_LINE_INPUT | windows.ENABLE_PROCESSED_INPUT)
+	raw |= windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := windows.SetConsoleMode(in, raw); err != nil {
+		return nil, err
+	}
+
+	out := windows.Stdout
+	var outMode uint32
+	if windows.GetConsoleMode(out, &outMode) == nil {
+		_ = windows.SetConsoleMode(out, outMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+
+	return func() error {
+		return windows.SetConsoleMode(in, old)
+	}, nil
+}
+
+func termWidth(fd uintptr) int {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(fd), &info); err != nil {
+		return 0
+	}
+
+	return int(info.Window.Right - info.Window.Left + 1)
+}
@@ -0,0 +1,47 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+)
+
+// eventTimeLayout is used for event timestamps in line formats.
+const eventTimeLayout = "2006-01-02 15:04:05"
+
+// eventRecord is the JSON shape of a streamed server message.
+type eventRecord struct {
+	Time time.Time `json:"time"`
+	beparser.Event
+}
+
+// PrintEvent writes a single server message event received at ts to w as
+// one line: NDJSON for FormatJSON, timestamp and raw line for
+// FormatPlain, and a timestamped, type-tagged line for the other formats.
+func PrintEvent(w io.Writer, ts time.Time, ev beparser.Event, format Format) error {
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(eventRecord{Time: ts, Event: ev})
+
+	case FormatPlain:
+		_, err := fmt.Fprintf(w, "%s %s\n", ts.Format(time.RFC3339), ev.Raw)
+		return err
+
+	case FormatMarkdown:
+		_, err := fmt.Fprintf(w, "- `%s` **%s** %s\n", ts.Format(eventTimeLayout), ev.Type, ev.Raw)
+		return err
+
+	case FormatHTML:
+		_, err := fmt.Fprintf(w, "<div><time>%s</time> <b>%s</b> %s</div>\n",
+			ts.Format(eventTimeLayout), ev.Type, html.EscapeString(ev.Raw))
+		return err
+
+	default:
+		_, err := fmt.Fprintf(w, "%s  %-12s  %s\n", ts.Format(eventTimeLayout), ev.Type, ev.Raw)
+		return err
+	}
+}
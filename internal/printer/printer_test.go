@@ -2,9 +2,13 @@ package printer
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
 )
 
 func loadData(t *testing.T, name string) []byte {
@@ -74,3 +78,41 @@ func TestPrinter_AllExamples(t *testing.T) {
 		}
 	}
 }
+
+func TestPrintEvent(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ev := beparser.ParseEvent([]byte("(Global) Survivor: <hi>"))
+
+	cases := []struct {
+		want   string
+		format Format
+	}{
+		{"2026-01-02 03:04:05  chat          (Global) Survivor: <hi>\n", FormatTable},
+		{"2026-01-02T03:04:05Z (Global) Survivor: <hi>\n", FormatPlain},
+		{"- `2026-01-02 03:04:05` **chat** (Global) Survivor: <hi>\n", FormatMarkdown},
+		{"<div><time>2026-01-02 03:04:05</time> <b>chat</b> (Global) Survivor: &lt;hi&gt;</div>\n", FormatHTML},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := PrintEvent(&buf, ts, ev, c.format); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != c.want {
+			t.Errorf("format %d: got %q, want %q", c.format, buf.String(), c.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := PrintEvent(&buf, ts, ev, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("not JSON: %q", buf.String())
+	}
+	if rec["type"] != "chat" || rec["channel"] != "Global" || rec["time"] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected record: %v", rec)
+	}
+}
@@ -0,0 +1,383 @@
+/*
+Package proxy shares one BattlEye RCON session between many RCON clients.
+
+A Proxy holds a single upstream bercon.Connection and serves the BattlEye
+RCON protocol on a UDP socket. Downstream clients log in with their own
+passwords, checked by an Authenticator, and keep their own sequence
+numbers: every command is sent upstream through the shared connection,
+which allocates upstream sequence numbers, and the response is returned
+under the sequence number the client used, split into multipart packets
+when it is large. Server messages are broadcast to every logged-in client
+with a per-client message sequence and resent until acknowledged.
+*/
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+const (
+	// ClientTimeout is how long a client may stay silent before it is
+	// dropped, as BattlEye does with idle RCON clients.
+	ClientTimeout = 45 * time.Second
+
+	// pageSize is the largest response chunk in one datagram; it fits the
+	// default bercon receive buffer.
+	pageSize = 1000
+
+	// maxDatagram is the read buffer for downstream packets.
+	maxDatagram = 64 * 1024
+
+	// resendInterval and maxSends control redelivery of unacknowledged
+	// messages.
+	resendInterval = 2 * time.Second
+	maxSends       = 5
+)
+
+// ErrUpstreamClosed is returned by Serve when the upstream connection is
+// closed and will not reconnect.
+var ErrUpstreamClosed = errors.New("upstream connection closed")
+
+// Authenticator maps the login password of a downstream client to a
+// client name used in logs and by the Authorizer.
+type Authenticator func(password string) (client string, ok bool)
+
+// Authorizer decides whether client may run command. A returned error is
+// sent to the client as the command response instead of forwarding it.
+type Authorizer func(client, command string) error
+
+// Proxy serves downstream RCON clients over one upstream connection.
+type Proxy struct {
+	upstream *bercon.Connection
+	auth     Authenticator
+	authz    Authorizer
+	logger   *slog.Logger
+	pc       net.PacketConn
+	clients  map[string]*client
+	timeout  time.Duration
+	mu       sync.Mutex
+}
+
+// client is a logged-in downstream client. All fields except addr and
+// name are guarded by Proxy.mu.
+type client struct {
+	addr     net.Addr
+	lastSeen time.Time
+	inflight map[byte]struct{}
+	unacked  map[byte]*message
+	name     string
+	msgSeq   byte
+}
+
+// message is a broadcast message waiting for the client's ack.
+type message struct {
+	sent  time.Time
+	raw   []byte
+	sends int
+}
+
+// New returns a proxy for upstream that admits clients accepted by auth.
+func New(upstream *bercon.Connection, auth Authenticator) *Proxy {
+	return &Proxy{
+		upstream: upstream,
+		auth:     auth,
+		logger:   slog.New(slog.DiscardHandler),
+		clients:  make(map[string]*client),
+	}
+}
+
+// SetAuthorizer sets the per-command check. nil allows every command.
+func (p *Proxy) SetAuthorizer(a Authorizer) {
+	p.authz = a
+}
+
+// SetLogger sets the logger for logins, commands and dropped clients.
+// nil disables logging.
+func (p *Proxy) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	p.logger = l
+}
+
+// SetTimeout sets the upstream timeout per forwarded command. Zero uses
+// the deadline of the upstream connection.
+func (p *Proxy) SetTimeout(d time.Duration) {
+	p.timeout = max(d, 0)
+}
+
+// Clients returns the names of the logged-in clients.
+func (p *Proxy) Clients() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.clients))
+	for _, c := range p.clients {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// Serve handles downstream clients on pc until ctx is done, pc fails or
+// the upstream connection stops. pc is closed on return. Serve must be
+// called at most once.
+func (p *Proxy) Serve(ctx context.Context, pc net.PacketConn) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	p.pc = pc
+	sub := p.upstream.Subscribe(bercon.IsMessage, bercon.DefaultMessagesBufferSize, bercon.DropOldest)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	wg.Go(func() {
+		<-ctx.Done()
+		_ = pc.Close()
+		sub.Unsubscribe()
+	})
+	wg.Go(func() { p.broadcast(ctx, sub, cancel) })
+	wg.Go(func() { p.housekeep(ctx) })
+
+	buf := make([]byte, maxDatagram)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				cancel(err)
+				return err
+			}
+			if cause := context.Cause(ctx); !errors.Is(cause, context.Canceled) {
+				return cause
+			}
+			return nil
+		}
+
+		pkt, err := bercon.DecodePacket(buf[:n])
+		if err != nil {
+			p.logger.Debug("bad packet", "addr", addr.String(), "error", err)
+			continue
+		}
+		p.handle(ctx, addr, pkt)
+	}
+}
+
+// handle dispatches one downstream packet. NOTE: called only from the
+// Serve read loop.
+func (p *Proxy) handle(ctx context.Context, addr net.Addr, pkt bercon.Packet) {
+	if pkt.Kind == bercon.LoginPacket {
+		p.login(addr, string(pkt.Data))
+		return
+	}
+
+	p.mu.Lock()
+	c := p.clients[addr.String()]
+	if c == nil {
+		p.mu.Unlock()
+		return
+	}
+	c.lastSeen = time.Now()
+
+	switch pkt.Kind {
+	case bercon.MessagePacket:
+		delete(c.unacked, pkt.Seq)
+		p.mu.Unlock()
+
+	case bercon.CommandPacket:
+		_, busy := c.inflight[pkt.Seq]
+		if !busy && len(pkt.Data) > 0 {
+			c.inflight[pkt.Seq] = struct{}{}
+		}
+		p.mu.Unlock()
+
+		switch {
+		case busy:
+			// retransmission of a command still being forwarded
+		case len(pkt.Data) == 0:
+			// keepalive, answered locally
+			p.reply(addr, pkt.Seq, nil)
+		default:
+			go p.forward(ctx, c, pkt.Seq, string(pkt.Data))
+		}
+
+	default:
+		p.mu.Unlock()
+	}
+}
+
+// login checks the password and registers the client, replacing an
+// earlier session from the same address.
+func (p *Proxy) login(addr net.Addr, password string) {
+	name, ok := p.auth(password)
+
+	p.mu.Lock()
+	key := addr.String()
+	if ok {
+		p.clients[key] = &client{
+			addr:     addr,
+			name:     name,
+			lastSeen: time.Now(),
+			inflight: make(map[byte]struct{}),
+			unacked:  make(map[byte]*message),
+		}
+	} else {
+		delete(p.clients, key)
+	}
+	p.mu.Unlock()
+
+	result := byte(0x00)
+	if ok {
+		result = 0x01
+		p.logger.Info("client logged in", "client", name, "addr", key)
+	} else {
+		p.logger.Warn("client login failed", "addr", key)
+	}
+	p.write(addr, bercon.EncodePacket(bercon.Packet{Kind: bercon.LoginPacket, Data: []byte{result}}))
+}
+
+// forward sends command upstream and returns the response to the client
+// under its own sequence number. A failed command is answered with the
+// error text, as a denied one is.
+func (p *Proxy) forward(ctx context.Context, c *client, seq byte, command string) {
+	defer func() {
+		p.mu.Lock()
+		delete(c.inflight, seq)
+		p.mu.Unlock()
+	}()
+
+	if p.authz != nil {
+		if err := p.authz(c.name, command); err != nil {
+			p.reply(c.addr, seq, []byte(err.Error()))
+			return
+		}
+	}
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	p.logger.Info("command", "client", c.name, "command", command)
+	resp, err := p.upstream.SendContext(ctx, command)
+	if err != nil {
+		p.logger.Warn("command failed", "client", c.name, "command", command, "error", err)
+		resp = []byte(err.Error())
+	}
+
+	p.reply(c.addr, seq, resp)
+}
+
+// reply sends a command response, paginated when needed.
+func (p *Proxy) reply(addr net.Addr, seq byte, resp []byte) {
+	for _, pkt := range bercon.SplitResponse(seq, resp, pageSize) {
+		p.write(addr, bercon.EncodePacket(pkt))
+	}
+}
+
+// broadcast relays upstream server messages to every logged-in client.
+// When the upstream connection stops for good it cancels Serve.
+func (p *Proxy) broadcast(ctx context.Context, sub *bercon.Subscription, stop context.CancelCauseFunc) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case ev, ok := <-sub.C:
+			if !ok {
+				err := ErrUpstreamClosed
+				if cause := p.upstream.Err(); cause != nil {
+					err = errors.Join(err, cause)
+				}
+				stop(err)
+				return
+			}
+
+			type delivery struct {
+				addr net.Addr
+				raw  []byte
+			}
+
+			now := time.Now()
+			p.mu.Lock()
+			out := make([]delivery, 0, len(p.clients))
+			for _, c := range p.clients {
+				raw := bercon.EncodePacket(bercon.Packet{Kind: bercon.MessagePacket, Seq: c.msgSeq, Data: ev.Data})
+				c.unacked[c.msgSeq] = &message{raw: raw, sent: now, sends: 1}
+				c.msgSeq++
+				out = append(out, delivery{addr: c.addr, raw: raw})
+			}
+			p.mu.Unlock()
+
+			for _, d := range out {
+				p.write(d.addr, d.raw)
+			}
+		}
+	}
+}
+
+// housekeep drops idle clients and resends unacknowledged messages.
+func (p *Proxy) housekeep(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.sweep(now)
+		}
+	}
+}
+
+// sweep runs one housekeeping pass at now.
+func (p *Proxy) sweep(now time.Time) {
+	type resend struct {
+		addr net.Addr
+		raw  []byte
+	}
+	var out []resend
+
+	p.mu.Lock()
+	for key, c := range p.clients {
+		if now.Sub(c.lastSeen) > ClientTimeout {
+			delete(p.clients, key)
+			p.logger.Info("client timed out", "client", c.name, "addr", key)
+			continue
+		}
+
+		for seq, m := range c.unacked {
+			if now.Sub(m.sent) < resendInterval {
+				continue
+			}
+			if m.sends >= maxSends {
+				delete(c.unacked, seq)
+				p.logger.Debug("message not acknowledged", "client", c.name, "seq", seq)
+				continue
+			}
+			m.sent = now
+			m.sends++
+			out = append(out, resend{addr: c.addr, raw: m.raw})
+		}
+	}
+	p.mu.Unlock()
+
+	for _, r := range out {
+		p.write(r.addr, r.raw)
+	}
+}
+
+// write sends one datagram to a client.
+func (p *Proxy) write(addr net.Addr, raw []byte) {
+	if _, err := p.pc.WriteTo(raw, addr); err != nil {
+		p.logger.Debug("write failed", "addr", addr.String(), "error", err)
+	}
+}
@@ -0,0 +1,286 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+	"github.com/woozymasta/bercon-cli/pkg/bercon/bercontest"
+)
+
+// startProxy runs a proxy for srv that admits the passwords "pw-a" and
+// "pw-b" and returns its UDP address.
+func startProxy(t *testing.T, srv *bercontest.Server, authz Authorizer) (*Proxy, string) {
+	t.Helper()
+
+	upstream, err := bercon.OpenWithOptions(srv.Addr, "pw", bercon.WithDeadline(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = upstream.Close() })
+
+	p := New(upstream, func(password string) (string, bool) {
+		name, ok := map[string]string{"pw-a": "alice", "pw-b": "bob"}[password]
+		return name, ok
+	})
+	p.SetAuthorizer(authz)
+
+	return p, serve(t, p)
+}
+
+// serve runs p on a local UDP socket until the test ends and returns its
+// address.
+func serve(t *testing.T, p *Proxy) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Serve(ctx, pc) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	return pc.LocalAddr().String()
+}
+
+func dial(t *testing.T, addr, password string) *bercon.Connection {
+	t.Helper()
+
+	conn, err := bercon.OpenWithOptions(addr, password, bercon.WithDeadline(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestProxyCommands(t *testing.T) {
+	srv := bercontest.NewServer("pw")
+	defer func() { _ = srv.Close() }()
+	big := strings.Repeat("0123456789", 350)
+	srv.Respond("big", big)
+
+	p, addr := startProxy(t, srv, func(client, command string) error {
+		if client == "bob" && strings.HasPrefix(command, "#") {
+			return errors.New("permission denied")
+		}
+		return nil
+	})
+
+	a := dial(t, addr, "pw-a")
+	b := dial(t, addr, "pw-b")
+
+	if _, err := bercon.OpenWithOptions(addr, "wrong", bercon.WithDeadline(time.Second)); !errors.Is(err, bercon.ErrLoginFailed) {
+		t.Fatalf("wrong password: err = %v, want ErrLoginFailed", err)
+	}
+
+	want, err := dial(t, srv.Addr, "pw").Send("players")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, conn := range []*bercon.Connection{a, b, a, b} {
+		wg.Go(func() {
+			for range 5 {
+				got, err := conn.Send("players")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if string(got) != string(want) {
+					t.Errorf("players = %q, want %q", got, want)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	got, err := a.Send("big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != big {
+		t.Errorf("multipart response has %d bytes, want %d", len(got), len(big))
+	}
+
+	got, err = b.Send("#lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "permission denied" {
+		t.Errorf("denied command = %q", got)
+	}
+	for _, cmd := range srv.Commands() {
+		if cmd == "#lock" {
+			t.Error("denied command was forwarded")
+		}
+	}
+
+	if ok, _ := srv.Logins(); ok != 2 {
+		t.Errorf("upstream logins = %d, want 2 (proxy and reference client)", ok)
+	}
+	if n := len(p.Clients()); n != 2 {
+		t.Errorf("clients = %d, want 2", n)
+	}
+}
+
+func TestProxyUpstreamError(t *testing.T) {
+	srv, upstream := bercontest.Open(t, "pw", bercon.WithDeadline(5*time.Second))
+	srv.SetDrop(bercontest.DropCommand("bans", 1))
+
+	p := New(upstream, func(string) (string, bool) { return "alice", true })
+	p.SetTimeout(100 * time.Millisecond)
+	addr := serve(t, p)
+
+	got, err := dial(t, addr, "pw-a").Send("bans")
+	if err != nil {
+		t.Fatalf("client got no reply: %v", err)
+	}
+	if !strings.Contains(string(got), context.DeadlineExceeded.Error()) {
+		t.Errorf("failed command = %q, want the upstream error", got)
+	}
+}
+
+func TestProxyBroadcast(t *testing.T) {
+	srv := bercontest.NewServer("pw")
+	defer func() { _ = srv.Close() }()
+
+	_, addr := startProxy(t, srv, nil)
+
+	conns := []*bercon.Connection{dial(t, addr, "pw-a"), dial(t, addr, "pw-b")}
+	subs := make([]*bercon.Subscription, len(conns))
+	for i, conn := range conns {
+		subs[i] = conn.Subscribe(bercon.IsMessage, 8, bercon.Block)
+	}
+
+	for _, msg := range []string{"Player #1 Survivor disconnected", "(Global) Survivor: hi"} {
+		if n := srv.Push(msg); n != 1 {
+			t.Fatalf("pushed to %d upstream clients, want 1", n)
+		}
+
+		for i, sub := range subs {
+			select {
+			case ev := <-sub.C:
+				if string(ev.Data) != msg {
+					t.Errorf("client %d got %q, want %q", i, ev.Data, msg)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("client %d: no message", i)
+			}
+		}
+	}
+
+	if !srv.WaitAcked(time.Second) {
+		t.Error("upstream messages not acknowledged")
+	}
+}
+
+func TestProxyUpstreamLost(t *testing.T) {
+	srv := bercontest.NewServer("pw")
+	defer func() { _ = srv.Close() }()
+
+	upstream, err := bercon.OpenWithOptions(srv.Addr, "pw",
+		bercon.WithKeepalive(200*time.Millisecond),
+		bercon.WithDeadline(200*time.Millisecond),
+		bercon.WithReconnect(bercon.ReconnectPolicy{
+			Backoff:     20 * time.Millisecond,
+			MaxAttempts: 2,
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = upstream.Close() }()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := New(upstream, func(string) (string, bool) { return "alice", true })
+	done := make(chan error, 1)
+	go func() { done <- p.Serve(context.Background(), pc) }()
+
+	// the upstream server goes away without the proxy closing upstream
+	_ = srv.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrUpstreamClosed) || !errors.Is(err, bercon.ErrReconnectFailed) {
+			t.Fatalf("Serve: err = %v, want ErrUpstreamClosed and ErrReconnectFailed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve still running after the upstream stopped")
+	}
+
+	if _, err := pc.WriteTo(nil, pc.LocalAddr()); err == nil {
+		t.Error("downstream socket still open")
+	}
+}
+
+func TestProxySweep(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	peer, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = peer.Close() }()
+
+	now := time.Now()
+	raw := bercon.EncodePacket(bercon.Packet{Kind: bercon.MessagePacket, Seq: 7, Data: []byte("hello")})
+
+	p := New(nil, nil)
+	p.pc = pc
+	p.clients["idle"] = &client{name: "idle", addr: peer.LocalAddr(), lastSeen: now.Add(-ClientTimeout - time.Second)}
+	p.clients["live"] = &client{
+		name:     "live",
+		addr:     peer.LocalAddr(),
+		lastSeen: now,
+		unacked: map[byte]*message{
+			7: {raw: raw, sent: now.Add(-resendInterval), sends: 1},
+			8: {raw: raw, sent: now.Add(-resendInterval), sends: maxSends},
+		},
+	}
+
+	p.sweep(now)
+
+	if names := p.Clients(); len(names) != 1 || names[0] != "live" {
+		t.Fatalf("clients = %v, want [live]", names)
+	}
+	if n := len(p.clients["live"].unacked); n != 1 {
+		t.Errorf("unacked = %d, want 1", n)
+	}
+
+	buf := make([]byte, 64)
+	_ = peer.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := peer.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := bercon.DecodePacket(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.Kind != bercon.MessagePacket || pkt.Seq != 7 || string(pkt.Data) != "hello" {
+		t.Errorf("resent %+v", pkt)
+	}
+}
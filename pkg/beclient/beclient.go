@@ -0,0 +1,221 @@
+/*
+Package beclient is a typed facade over BattlEye RCON commands.
+
+Instead of building command strings and parsing responses by hand:
+
+	data, _ := conn.Send("kick 3 AFK")
+	players := beparser.Parse(data, "players").(*beparser.Players)
+
+use the Client methods, which validate arguments and return beparser
+types:
+
+	cl := beclient.New(conn)
+	players, err := cl.Players(ctx)
+	err = cl.Kick(ctx, 3, "AFK")
+*/
+package beclient
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+	"github.com/woozymasta/dzid"
+)
+
+// Everyone addresses a Say message to all players.
+const Everyone = -1
+
+// Sender sends a raw RCON command and returns the response.
+// *bercon.Connection implements it.
+type Sender interface {
+	SendContext(ctx context.Context, command string) ([]byte, error)
+}
+
+// Client runs typed BattlEye RCON commands over a Sender.
+type Client struct {
+	conn Sender
+}
+
+// New returns a Client sending commands over conn.
+func New(conn Sender) *Client {
+	return &Client{conn: conn}
+}
+
+// Players returns the players on the server.
+func (c *Client) Players(ctx context.Context) (*beparser.Players, error) {
+	data, err := c.conn.SendContext(ctx, "players")
+	if err != nil {
+		return nil, err
+	}
+
+	players := beparser.NewPlayers()
+	players.Parse(data)
+
+	return players, nil
+}
+
+// Bans returns the GUID and IP bans.
+func (c *Client) Bans(ctx context.Context) (*beparser.Bans, error) {
+	data, err := c.conn.SendContext(ctx, "bans")
+	if err != nil {
+		return nil, err
+	}
+
+	bans := beparser.NewBans()
+	bans.Parse(data)
+
+	return bans, nil
+}
+
+// Admins returns the connected RCon admins.
+func (c *Client) Admins(ctx context.Context) (*beparser.Admins, error) {
+	data, err := c.conn.SendContext(ctx, "admins")
+	if err != nil {
+		return nil, err
+	}
+
+	admins := beparser.NewAdmins()
+	admins.Parse(data)
+
+	return admins, nil
+}
+
+// Kick kicks player id with an optional reason.
+func (c *Client) Kick(ctx context.Context, id int, reason string) error {
+	if id < 0 {
+		return ErrPlayerID
+	}
+	if err := checkText(reason); err != nil {
+		return err
+	}
+
+	return c.exec(ctx, "kick", strconv.Itoa(id), reason)
+}
+
+// Ban bans player id for minutes (0 is permanent) with an optional reason.
+func (c *Client) Ban(ctx context.Context, id, minutes int, reason string) error {
+	if id < 0 {
+		return ErrPlayerID
+	}
+	if minutes < 0 {
+		return ErrDuration
+	}
+	if err := checkText(reason); err != nil {
+		return err
+	}
+
+	return c.exec(ctx, "ban", strconv.Itoa(id), strconv.Itoa(minutes), reason)
+}
+
+// AddBan bans a BattlEye GUID or IPv4 address that does not have to be
+// online, for d rounded up to whole minutes (0 is permanent), with an
+// optional reason.
+func (c *Client) AddBan(ctx context.Context, target string, d time.Duration, reason string) error {
+	target = strings.TrimSpace(target)
+	if !isBanTarget(target) {
+		return ErrBanTarget
+	}
+	if d < 0 {
+		return ErrDuration
+	}
+	if err := checkText(reason); err != nil {
+		return err
+	}
+
+	minutes := int64(math.Ceil(d.Minutes()))
+
+	return c.exec(ctx, "addBan", target, strconv.FormatInt(minutes, 10), reason)
+}
+
+// RemoveBan removes ban number id as listed by Bans.
+func (c *Client) RemoveBan(ctx context.Context, id int) error {
+	if id < 0 {
+		return ErrBanID
+	}
+
+	return c.exec(ctx, "removeBan", strconv.Itoa(id))
+}
+
+// Say sends msg to player id, or to all players with Everyone.
+func (c *Client) Say(ctx context.Context, id int, msg string) error {
+	if id < Everyone {
+		return ErrPlayerID
+	}
+	if strings.TrimSpace(msg) == "" {
+		return ErrEmptyMessage
+	}
+	if err := checkText(msg); err != nil {
+		return err
+	}
+
+	return c.exec(ctx, "say", strconv.Itoa(id), msg)
+}
+
+// LoadBans reloads bans from bans.txt.
+func (c *Client) LoadBans(ctx context.Context) error {
+	return c.exec(ctx, "loadBans")
+}
+
+// WriteBans saves the current bans to bans.txt.
+func (c *Client) WriteBans(ctx context.Context) error {
+	return c.exec(ctx, "writeBans")
+}
+
+// LoadScripts reloads scripts.txt.
+func (c *Client) LoadScripts(ctx context.Context) error {
+	return c.exec(ctx, "loadScripts")
+}
+
+// LoadEvents reloads the event filters.
+func (c *Client) LoadEvents(ctx context.Context) error {
+	return c.exec(ctx, "loadEvents")
+}
+
+// MaxPing sets the maximum ping allowed before players are kicked.
+func (c *Client) MaxPing(ctx context.Context, ping int) error {
+	if ping <= 0 {
+		return ErrPing
+	}
+
+	return c.exec(ctx, "MaxPing", strconv.Itoa(ping))
+}
+
+// exec joins non-empty args into a command and sends it.
+func (c *Client) exec(ctx context.Context, args ...string) error {
+	parts := args[:0:0]
+	for _, a := range args {
+		if a != "" {
+			parts = append(parts, a)
+		}
+	}
+
+	_, err := c.conn.SendContext(ctx, strings.Join(parts, " "))
+	return err
+}
+
+// checkText rejects text that would break the single-line command.
+func checkText(s string) error {
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return ErrText
+		}
+	}
+
+	return nil
+}
+
+// isBanTarget reports whether s is a BattlEye GUID or an IPv4 address.
+func isBanTarget(s string) bool {
+	if dzid.IsBattlEye(s) {
+		return true
+	}
+
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() != nil
+}
@@ -0,0 +1,117 @@
+package beclient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/beclient"
+	"github.com/woozymasta/bercon-cli/pkg/bercon/bercontest"
+)
+
+func newClient(t *testing.T) (*bercontest.Server, *beclient.Client) {
+	t.Helper()
+
+	srv, conn := bercontest.Open(t, "secret")
+	return srv, beclient.New(conn)
+}
+
+func TestQueries(t *testing.T) {
+	_, cl := newClient(t)
+	ctx := context.Background()
+
+	players, err := cl.Players(ctx)
+	if err != nil || len(*players) != 4 {
+		t.Fatalf("players: %v, %v", players, err)
+	}
+
+	bans, err := cl.Bans(ctx)
+	if err != nil || len(bans.GUIDBans) != 2 || len(bans.IPBans) != 2 {
+		t.Fatalf("bans: %+v, %v", bans, err)
+	}
+
+	admins, err := cl.Admins(ctx)
+	if err != nil || len(*admins) != 2 {
+		t.Fatalf("admins: %v, %v", admins, err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	srv, cl := newClient(t)
+	ctx := context.Background()
+
+	calls := []func() error{
+		func() error { return cl.Kick(ctx, 3, "AFK too long") },
+		func() error { return cl.Kick(ctx, 4, "") },
+		func() error { return cl.Ban(ctx, 2, 0, "cheating") },
+		func() error { return cl.AddBan(ctx, "77C8F104322269B21FA015C5ED6977F8", 90*time.Second, "alt") },
+		func() error { return cl.AddBan(ctx, "192.0.2.1", 0, "") },
+		func() error { return cl.RemoveBan(ctx, 5) },
+		func() error { return cl.Say(ctx, beclient.Everyone, "restart in 5 minutes") },
+		func() error { return cl.LoadBans(ctx) },
+		func() error { return cl.WriteBans(ctx) },
+		func() error { return cl.LoadScripts(ctx) },
+		func() error { return cl.LoadEvents(ctx) },
+		func() error { return cl.MaxPing(ctx, 250) },
+	}
+	for i, call := range calls {
+		if err := call(); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+
+	want := []string{
+		"kick 3 AFK too long",
+		"kick 4",
+		"ban 2 0 cheating",
+		"addBan 77C8F104322269B21FA015C5ED6977F8 2 alt",
+		"addBan 192.0.2.1 0",
+		"removeBan 5",
+		"say -1 restart in 5 minutes",
+		"loadBans",
+		"writeBans",
+		"loadScripts",
+		"loadEvents",
+		"MaxPing 250",
+	}
+	got := srv.Commands()
+	if len(got) != len(want) {
+		t.Fatalf("got commands %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("command %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	srv, cl := newClient(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		err  error
+		call func() error
+	}{
+		{beclient.ErrPlayerID, func() error { return cl.Kick(ctx, -1, "") }},
+		{beclient.ErrText, func() error { return cl.Kick(ctx, 1, "line\nbreak") }},
+		{beclient.ErrDuration, func() error { return cl.Ban(ctx, 1, -5, "") }},
+		{beclient.ErrBanTarget, func() error { return cl.AddBan(ctx, "not-a-guid", 0, "") }},
+		{beclient.ErrBanTarget, func() error { return cl.AddBan(ctx, "::1", 0, "") }},
+		{beclient.ErrDuration, func() error { return cl.AddBan(ctx, "192.0.2.1", -time.Minute, "") }},
+		{beclient.ErrBanID, func() error { return cl.RemoveBan(ctx, -1) }},
+		{beclient.ErrPlayerID, func() error { return cl.Say(ctx, -2, "hi") }},
+		{beclient.ErrEmptyMessage, func() error { return cl.Say(ctx, 1, "  ") }},
+		{beclient.ErrPing, func() error { return cl.MaxPing(ctx, 0) }},
+	}
+	for i, tc := range cases {
+		if err := tc.call(); !errors.Is(err, tc.err) {
+			t.Fatalf("case %d: got %v, want %v", i, err, tc.err)
+		}
+	}
+
+	if got := srv.Commands(); len(got) != 0 {
+		t.Fatalf("invalid calls reached the server: %q", got)
+	}
+}
@@ -0,0 +1,28 @@
+package beclient
+
+import "errors"
+
+var (
+	// ErrPlayerID is returned when a player number is negative.
+	ErrPlayerID = errors.New("invalid player number")
+
+	// ErrBanID is returned when a ban number is negative.
+	ErrBanID = errors.New("invalid ban number")
+
+	// ErrBanTarget is returned when an AddBan target is neither a BattlEye
+	// GUID nor an IPv4 address.
+	ErrBanTarget = errors.New("ban target must be a BattlEye GUID or IPv4 address")
+
+	// ErrDuration is returned when a ban duration is negative.
+	ErrDuration = errors.New("invalid ban duration")
+
+	// ErrPing is returned when a max ping value is not positive.
+	ErrPing = errors.New("invalid max ping")
+
+	// ErrEmptyMessage is returned when a chat message is empty.
+	ErrEmptyMessage = errors.New("message is empty")
+
+	// ErrText is returned when a reason or message contains line breaks
+	// or other control characters.
+	ErrText = errors.New("text contains control characters")
+)
@@ -1,7 +1,8 @@
 // Package beparser parses BattlEye RCON command responses ("players",
 // "admins", "bans", and generic messages). It also supports enriching
 // results with GeoIP (Country/City/coordinates) when a GeoLite2/GeoIP2
-// database is provided.
+// database is provided. ParseEvent classifies server-pushed messages
+// (connects, GUIDs, kicks, bans, chat, admin logins) into typed events.
 package beparser
 
 import (
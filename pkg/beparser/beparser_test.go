@@ -271,3 +271,87 @@ func Test_getMinutes(t *testing.T) {
 		}
 	}
 }
+
+func TestParseEvent(t *testing.T) {
+	const guid = "77c8f104322269b21fa015c5ed6977f8"
+
+	cases := []struct {
+		in   string
+		want Event
+	}{
+		{
+			"Player #3 Survivor (192.0.2.10:2304) connected",
+			Event{Type: EventConnected, ID: 3, Name: "Survivor", IP: "192.0.2.10", Port: 2304},
+		},
+		{
+			"Player #12 John (Doe) (192.0.2.11:61022) connected",
+			Event{Type: EventConnected, ID: 12, Name: "John (Doe)", IP: "192.0.2.11", Port: 61022},
+		},
+		{
+			"Player #3 Survivor - BE GUID: " + guid,
+			Event{Type: EventGUID, ID: 3, Name: "Survivor", GUID: guid},
+		},
+		{
+			"Player #3 Survivor - GUID: 77C8F104322269B21FA015C5ED6977F8 (unverified)",
+			Event{Type: EventGUID, ID: 3, Name: "Survivor", GUID: guid},
+		},
+		{
+			"Verified GUID (" + guid + ") of player #3 Survivor",
+			Event{Type: EventGUID, ID: 3, Name: "Survivor", GUID: guid, Verified: true},
+		},
+		{
+			"Player #3 Survivor disconnected",
+			Event{Type: EventDisconnected, ID: 3, Name: "Survivor"},
+		},
+		{
+			"Player #3 Survivor (" + guid + ") has been kicked by BattlEye: Admin Kick (AFK)",
+			Event{Type: EventKicked, ID: 3, Name: "Survivor", GUID: guid, Reason: "AFK"},
+		},
+		{
+			"Player #3 Survivor (" + guid + ") has been kicked by BattlEye: Client not responding",
+			Event{Type: EventKicked, ID: 3, Name: "Survivor", GUID: guid, Reason: "Client not responding"},
+		},
+		{
+			"Player #3 Survivor (" + guid + ") has been kicked by BattlEye: Admin Ban (cheating)",
+			Event{Type: EventBanned, ID: 3, Name: "Survivor", GUID: guid, Reason: "cheating"},
+		},
+		{
+			"Player #3 Survivor (" + guid + ") has been kicked by BattlEye: Global Ban #a1b2c3",
+			Event{Type: EventBanned, ID: 3, Name: "Survivor", GUID: guid, Reason: "Global Ban #a1b2c3"},
+		},
+		{
+			"Player #3 Survivor (" + guid + ") has been kicked by BattlEye: Script Restriction #42",
+			Event{
+				Type: EventFilterKick, ID: 3, Name: "Survivor", GUID: guid,
+				Reason: "Script Restriction #42", Filter: "Script", Restriction: 42,
+			},
+		},
+		{
+			"(Global) Survivor: hello: all",
+			Event{Type: EventChat, ID: -1, Name: "Survivor", Channel: ChannelGlobal, Text: "hello: all"},
+		},
+		{
+			"(Vehicle) Bandit: go",
+			Event{Type: EventChat, ID: -1, Name: "Bandit", Channel: ChannelVehicle, Text: "go"},
+		},
+		{
+			"RCon admin #1 (127.0.0.1:51234) logged in",
+			Event{Type: EventAdminLogin, ID: 1, IP: "127.0.0.1", Port: 51234},
+		},
+		{
+			"RCon admin #0: (Global) restart in 5 minutes",
+			Event{Type: EventAdminChat, ID: 0, Channel: ChannelGlobal, Text: "restart in 5 minutes"},
+		},
+		{
+			"Something else entirely",
+			Event{Type: EventUnknown, ID: -1},
+		},
+	}
+
+	for _, c := range cases {
+		c.want.Raw = c.in
+		if got := ParseEvent([]byte(c.in)); got != c.want {
+			t.Errorf("ParseEvent(%q)\n got %+v\nwant %+v", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package beparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EventType classifies a server message pushed over RCON.
+type EventType string
+
+// Server message event types.
+const (
+	EventConnected    EventType = "connected"    // player connected
+	EventGUID         EventType = "guid"         // player GUID computed or verified
+	EventDisconnected EventType = "disconnected" // player disconnected
+	EventKicked       EventType = "kicked"       // player kicked by admin or BattlEye
+	EventBanned       EventType = "banned"       // player kicked because of a ban
+	EventFilterKick   EventType = "filter_kick"  // player kicked by a BattlEye filter
+	EventChat         EventType = "chat"         // player chat message
+	EventAdminChat    EventType = "admin_chat"   // message sent by an RCon admin
+	EventAdminLogin   EventType = "admin_login"  // RCon admin logged in
+	EventUnknown      EventType = "unknown"      // any other message
+)
+
+// Chat channels.
+const (
+	ChannelGlobal  = "Global"
+	ChannelSide    = "Side"
+	ChannelDirect  = "Direct"
+	ChannelVehicle = "Vehicle"
+	ChannelGroup   = "Group"
+	ChannelCommand = "Command"
+	ChannelUnknown = "Unknown"
+)
+
+// Event is a classified server message. Only the fields that the message
+// carries are set; ID is -1 for messages without a player or admin number.
+type Event struct {
+	Type EventType `json:"type"`
+	Raw  string    `json:"raw"`
+
+	Name    string `json:"name,omitempty"`
+	IP      string `json:"ip,omitempty"`
+	GUID    string `json:"guid,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Filter  string `json:"filter,omitempty"` // filter name, e.g. "Script"
+
+	ID          int    `json:"id"`                    // player or admin number
+	Restriction int    `json:"restriction,omitempty"` // filter line number
+	Port        uint16 `json:"port,omitempty"`
+	Verified    bool   `json:"verified,omitempty"` // GUID verified by BattlEye
+}
+
+var (
+	reConnected    = regexp.MustCompile(`^Player #(\d+) (.+) \(([^()]+)\) connected$`)
+	reDisconnected = regexp.MustCompile(`^Player #(\d+) (.+) disconnected$`)
+	reGUID         = regexp.MustCompile(`^Player #(\d+) (.+) - (?:BE )?GUID: ([0-9A-Fa-f]{32})(?: \(unverified\))?$`)
+	reVerified     = regexp.MustCompile(`^Verified GUID \(([0-9A-Fa-f]{32})\) of player #(\d+) (.+)$`)
+	reKicked       = regexp.MustCompile(`^Player #(\d+) (.+) \(([^()]*)\) has been kicked by BattlEye: (.*)$`)
+	reRestriction  = regexp.MustCompile(`^(\w+) Restriction #(\d+)`)
+	reAdminReason  = regexp.MustCompile(`^Admin (Kick|Ban)(?: \((.*)\))?$`)
+	reAdminLogin   = regexp.MustCompile(`^RCon admin #(\d+) \(([^()]+)\) logged in$`)
+	reAdminChat    = regexp.MustCompile(`^RCon admin #(\d+): \(([^()]+)\) (.*)$`)
+	reChat         = regexp.MustCompile(`^\((Global|Side|Direct|Vehicle|Group|Command|Unknown)\) (.+?): (.*)$`)
+)
+
+// ParseEvent classifies a single server message line.
+func ParseEvent(data []byte) Event {
+	line := strings.TrimRight(string(data), "\r\n")
+	ev := Event{Type: EventUnknown, Raw: line, ID: -1}
+
+	if m := reChat.FindStringSubmatch(line); m != nil {
+		ev.Type = EventChat
+		ev.Channel, ev.Name, ev.Text = m[1], m[2], m[3]
+		return ev
+	}
+
+	if m := reConnected.FindStringSubmatch(line); m != nil {
+		ev.Type = EventConnected
+		ev.ID = atoi(m[1])
+		ev.Name = m[2]
+		ev.IP, ev.Port = parseAddress(m[3])
+		return ev
+	}
+
+	if m := reGUID.FindStringSubmatch(line); m != nil {
+		ev.Type = EventGUID
+		ev.ID = atoi(m[1])
+		ev.Name = m[2]
+		ev.GUID = strings.ToLower(m[3])
+		return ev
+	}
+
+	if m := reVerified.FindStringSubmatch(line); m != nil {
+		ev.Type = EventGUID
+		ev.GUID = strings.ToLower(m[1])
+		ev.ID = atoi(m[2])
+		ev.Name = m[3]
+		ev.Verified = true
+		return ev
+	}
+
+	if m := reKicked.FindStringSubmatch(line); m != nil {
+		ev.ID = atoi(m[1])
+		ev.Name = m[2]
+		if len(m[3]) == hashBytesGUID {
+			ev.GUID = strings.ToLower(m[3])
+		}
+		classifyKick(&ev, m[4])
+		return ev
+	}
+
+	if m := reDisconnected.FindStringSubmatch(line); m != nil {
+		ev.Type = EventDisconnected
+		ev.ID = atoi(m[1])
+		ev.Name = m[2]
+		return ev
+	}
+
+	if m := reAdminLogin.FindStringSubmatch(line); m != nil {
+		ev.Type = EventAdminLogin
+		ev.ID = atoi(m[1])
+		ev.IP, ev.Port = parseAddress(m[2])
+		return ev
+	}
+
+	if m := reAdminChat.FindStringSubmatch(line); m != nil {
+		ev.Type = EventAdminChat
+		ev.ID = atoi(m[1])
+		ev.Channel, ev.Text = m[2], m[3]
+		return ev
+	}
+
+	return ev
+}
+
+// classifyKick sets the event type and reason from a kick reason.
+func classifyKick(ev *Event, reason string) {
+	ev.Type = EventKicked
+	ev.Reason = reason
+
+	if m := reAdminReason.FindStringSubmatch(reason); m != nil {
+		if m[1] == "Ban" {
+			ev.Type = EventBanned
+		}
+		ev.Reason = m[2]
+		return
+	}
+
+	if m := reRestriction.FindStringSubmatch(reason); m != nil {
+		ev.Type = EventFilterKick
+		ev.Filter = m[1]
+		ev.Restriction = atoi(m[2])
+		return
+	}
+
+	if strings.HasPrefix(reason, "Global Ban") {
+		ev.Type = EventBanned
+	}
+}
+
+// atoi parses a number matched by \d+, returning -1 on overflow.
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
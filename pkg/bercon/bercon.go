@@ -2,18 +2,44 @@
 Package bercon implements a BattlEye RCON client: it opens a UDP
 session, performs login, sends commands, assembles (multi-part)
 responses, keeps the session alive, and exposes incoming server
-messages via a channel.
+messages via a channel and independent subscriptions.
 
 Key features
 
   - Single-writer event loop: all UDP writes and state mutations are
     serialized in the manager goroutine to avoid races.
-  - Multipart assembly: long command responses are reassembled in order
-    (with strict page/sequence checks).
+  - Multipart assembly: long command responses are reassembled by page
+    index, tolerating out-of-order and duplicated pages.
   - Keepalive: optional periodic “empty command” pings to keep the
     RCON session alive (BattlEye typically disconnects on long idle).
-  - Backpressure & deadlines: Send() waits for a free sequence or fails
-    fast with ErrBufferFull / ErrTimeout; reads are deadline-bound too.
+  - Retransmit: optional policy (SetRetransmit) that resends a command
+    with the same sequence when its response is lost, ignoring late
+    duplicates.
+  - Reconnect: optional policy (SetReconnect) that re-dials and logs in
+    again with backoff when the session is lost, keeping Messages open.
+  - Backpressure & deadlines: Send() is parked in a queue until a sequence
+    number is free (the manager keeps processing responses meanwhile) or
+    fails with ErrBufferFull / ErrTimeout; reads are deadline-bound too.
+    SendContext/OpenContext additionally honour context cancellation.
+  - Event bus: Subscribe(filter, bufferSize, policy) adds consumers with
+    their own buffer, DropNewest/DropOldest/Block policy and drop counter;
+    Messages is the default DropNewest subscriber; IsMessage filters out
+    login results after a reconnect.
+  - Observability: Stats() returns traffic, command outcome and RTT
+    counters for dashboards and health checks.
+  - Functional options: OpenWithOptions applies buffer size, deadlines,
+    login attempts, keepalive, local address, reconnect, retransmit,
+    state hooks and logger before the login handshake.
+  - Pluggable transport: OpenWithDialer, OpenWithConn and
+    OpenWithPacketConn run the protocol over any datagram transport;
+    the default is a connected UDP socket.
+  - Logging: an optional *slog.Logger set with WithLogger receives
+    debug/warn records for bad packets, retransmits, expired requests,
+    dropped messages, failed writes and reconnects; silent by default.
+  - Packet trace: WithTrace writes every raw datagram as NDJSON with
+    direction and timestamp; ReadTrace loads it for bercontest replay.
+  - Lifecycle: State() and OnStateChange() report connecting, logged-in,
+    idle, lost, reconnecting and closed transitions with their cause.
   - Typed errors: common protocol/transport problems have stable
     error values (see variables in errors.go).
 
@@ -22,11 +48,12 @@ Key features
 A single Connection owns:
   - A UDP reader loop that parses packets into typed structs.
   - A manager loop that:
-    – assigns free sequence numbers,
+    – assigns free sequence numbers (parking requests when all are busy),
     – writes packets,
     – assembles multi-part responses,
     – acks message packets,
-    – emits PacketEvent values to c.Messages.
+    – hands message packets to the dispatcher, which fans PacketEvent
+    values out to c.Messages and every Subscribe() consumer.
   - Send(command) is safe to call from multiple goroutines; responses
     are routed back to the caller.
 
@@ -40,8 +67,7 @@ Limits & sizes
 
 Use SetDeadlineTimeout to cap request/response round trips. Use
 SetKeepaliveTimeout(<45s) to keep a session alive (StartKeepAlive to
-enable). Use SetMicroSleepTimeout to trade CPU vs latency while waiting
-for a free sequence number under load.
+enable). Use SetMicroSleepTimeout to pause between login attempts.
 
 Quick start
 
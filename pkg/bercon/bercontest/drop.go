@@ -0,0 +1,48 @@
+package bercontest
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+// DropCommand returns a DropFunc that loses the first n inbound copies of
+// command, for example to exercise client retransmission.
+func DropCommand(command string, n int) DropFunc {
+	var mu sync.Mutex
+	return func(dir Direction, p bercon.Packet) bool {
+		if dir != Inbound || p.Kind != bercon.CommandPacket || string(p.Data) != command {
+			return false
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if n <= 0 {
+			return false
+		}
+		n--
+
+		return true
+	}
+}
+
+// LossRate returns a DropFunc that loses packets in both directions with
+// probability rate, using a deterministic source seeded with seed.
+// Login packets are never lost.
+func LossRate(rate float64, seed int64) DropFunc {
+	var mu sync.Mutex
+	rnd := rand.New(rand.NewSource(seed)) // #nosec G404 -- test loss, not security
+
+	return func(_ Direction, p bercon.Packet) bool {
+		if p.Kind == bercon.LoginPacket {
+			return false
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		return rnd.Float64() < rate
+	}
+}
@@ -0,0 +1,33 @@
+package bercontest
+
+// Players is the canned response to the "players" command: two verified
+// players in game, one in the lobby and one with an unverified GUID.
+const Players = `Players on server:
+[#] [IP Address]:[Port] [Ping] [GUID] [Name]
+--------------------------------------------------
+0   192.0.2.10:2304       47   77c8f104322269b21fa015c5ed6977f8(OK) Survivor
+1   192.0.2.11:2304       112  ce9f035e4564f50fee670651e63d6e50(OK) Bandit (Lobby)
+2   198.51.100.7:61022    31   8e89515ae9c31abf74ad4aa7bbd93044(OK) Medic
+3   203.0.113.5:2304      0    d350324f293a544772830945cd0c9057(?) Newcomer
+(4 players in total)`
+
+// Bans is the canned response to the "bans" command: two GUID bans and
+// two IP bans, permanent and timed.
+const Bans = `GUID Bans:
+[#] [GUID] [Minutes left] [Reason]
+----------------------------------------
+0  11111111111122222222222223333333 perm Cheating
+1  46738531902119115863919137179602 1440 Toxic behaviour
+
+IP Bans:
+[#] [IP Address] [Minutes left] [Reason]
+----------------------------------------------
+2  198.51.100.200  perm VPN abuse
+3  203.0.113.99    60   Spam`
+
+// Admins is the canned response to the "admins" command.
+const Admins = `Connected RCon admins:
+[#] [IP Address]:[Port]
+-----------------------------
+0 127.0.0.1:62676
+1 192.0.2.50:50021`
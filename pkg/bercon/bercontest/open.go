@@ -0,0 +1,25 @@
+package bercontest
+
+import (
+	"testing"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+// Open starts a server with password and returns it with a connection
+// logged in to it with opts. Both are closed when the test ends; a failed
+// login fails the test.
+func Open(tb testing.TB, password string, opts ...bercon.Option) (*Server, *bercon.Connection) {
+	tb.Helper()
+
+	srv := NewServer(password)
+	tb.Cleanup(func() { _ = srv.Close() })
+
+	c, err := bercon.OpenWithOptions(srv.Addr, password, opts...)
+	if err != nil {
+		tb.Fatalf("open: %v", err)
+	}
+	tb.Cleanup(func() { _ = c.Close() })
+
+	return srv, c
+}
@@ -0,0 +1,121 @@
+package bercontest
+
+import (
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+// replayStep holds the server packets that followed one client command
+// in a trace. The preamble step (after login) has no command.
+type replayStep struct {
+	command string
+	out     []replayPacket
+	seq     byte
+	used    bool
+}
+
+// replayPacket is a recorded server datagram. Packets that failed to
+// decode are replayed byte for byte.
+type replayPacket struct {
+	raw []byte
+	pkt bercon.Packet
+	ok  bool
+}
+
+// Replay makes the server reproduce a session recorded with
+// bercon.WithTrace. Any password is accepted, as traces do not contain
+// it. After login the server sends the packets recorded before the first
+// command; each command then gets the packets recorded after the same
+// command in the trace, in recorded order, including pushed messages,
+// multipart pages, duplicates and broken packets. Responses are re-sent
+// with the sequence number of the live request. Commands missing from the
+// trace fall back to the regular handlers.
+func (s *Server) Replay(records []bercon.TraceRecord) error {
+	steps := []*replayStep{{}}
+
+	for _, rec := range records {
+		raw, err := rec.Raw()
+		if err != nil {
+			return err
+		}
+
+		pkt, err := bercon.DecodePacket(raw)
+		rp := replayPacket{raw: raw, pkt: pkt, ok: err == nil}
+		current := steps[len(steps)-1]
+
+		if rec.Dir == bercon.TraceSent {
+			if rp.ok && pkt.Kind == bercon.CommandPacket {
+				steps = append(steps, &replayStep{command: string(pkt.Data), seq: pkt.Seq})
+			}
+			continue
+		}
+
+		switch {
+		case rp.ok && pkt.Kind == bercon.LoginPacket:
+			// login results are produced live
+
+		case rp.ok && pkt.Kind == bercon.CommandPacket:
+			// responses may follow later commands; match by sequence
+			target := current
+			for i := len(steps) - 1; i > 0; i-- {
+				if steps[i].seq == pkt.Seq {
+					target = steps[i]
+					break
+				}
+			}
+			target.out = append(target.out, rp)
+
+		default:
+			current.out = append(current.out, rp)
+		}
+	}
+
+	s.mu.Lock()
+	s.replay = steps
+	s.mu.Unlock()
+
+	return nil
+}
+
+// replayFor returns the recorded packets answering command line sent with
+// seq, or false if the trace has no unused step for it.
+// NOTE: must be called with s.mu held.
+func (s *Server) replayFor(p *peer, line string, seq byte) ([]outgoing, bool) {
+	if len(s.replay) < 2 {
+		return nil, false
+	}
+
+	for _, step := range s.replay[1:] {
+		if step.used || step.command != line {
+			continue
+		}
+
+		step.used = true
+		return s.replayPackets(p, step, seq), true
+	}
+
+	return nil, false
+}
+
+// replayPackets builds datagrams for a step, rewriting command response
+// sequences to seq and tracking replayed messages as unacknowledged.
+// NOTE: must be called with s.mu held.
+func (s *Server) replayPackets(p *peer, step *replayStep, seq byte) []outgoing {
+	out := make([]outgoing, 0, len(step.out))
+	for _, rp := range step.out {
+		o := outgoing{addr: p.addr, pkt: rp.pkt, raw: rp.raw}
+
+		if rp.ok {
+			switch rp.pkt.Kind {
+			case bercon.CommandPacket:
+				o.pkt.Seq = seq
+				o.raw = bercon.EncodePacket(o.pkt)
+			case bercon.MessagePacket:
+				p.unacked[rp.pkt.Seq] = rp.raw
+			}
+		}
+
+		out = append(out, o)
+	}
+
+	return out
+}
@@ -0,0 +1,433 @@
+/*
+Package bercontest provides an in-process fake BattlEye RCON server for
+testing clients without a game server.
+
+The server speaks the BattlEye RCON UDP wire format with CRC checks and
+supports:
+
+  - Login success and failure by password.
+  - Command responses, split into multipart packets above a page size.
+  - Pushed server messages with acknowledgement tracking and resending.
+  - Packet loss and multipart reordering injection.
+  - Canned "players", "bans" and "admins" fixtures, replaceable per command.
+  - Replay of packet traces recorded with bercon.WithTrace.
+
+Typical use:
+
+	srv := bercontest.NewServer("secret")
+	defer srv.Close()
+
+	conn, err := bercon.Open(srv.Addr, "secret")
+
+In tests, Open does both and closes them when the test ends:
+
+	srv, conn := bercontest.Open(t, "secret")
+*/
+package bercontest
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+// DefaultPageSize is the largest response body sent in a single packet.
+// It fits the default bercon receive buffer.
+const DefaultPageSize = 1000
+
+// UnknownCommand is the response to commands without a handler.
+const UnknownCommand = "Unknown command"
+
+// Direction of a packet relative to the server.
+type Direction int
+
+const (
+	// Inbound packets are sent by a client to the server.
+	Inbound Direction = iota
+
+	// Outbound packets are sent by the server to a client.
+	Outbound
+)
+
+// String returns the direction name.
+func (d Direction) String() string {
+	if d == Inbound {
+		return "in"
+	}
+	return "out"
+}
+
+// DropFunc reports whether a packet is lost on its way. It is called for
+// every valid packet in both directions from the server goroutines.
+type DropFunc func(dir Direction, p bercon.Packet) bool
+
+// Handler returns the response for a command line. It is called from the
+// server goroutine without the server lock held.
+type Handler func(command string) string
+
+// Server is a fake BattlEye RCON server listening on a local UDP port.
+type Server struct {
+	conn     *net.UDPConn
+	drop     DropFunc
+	handlers map[string]Handler
+	peers    map[string]*peer
+	replay   []*replayStep // set by Replay, preamble first
+
+	// Addr is the host:port the server listens on.
+	Addr     string
+	password string
+	commands []string
+
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	pageSize int
+	logins   int
+	failed   int
+	bad      int
+	reorder  bool
+}
+
+// peer is a client endpoint seen by the server.
+type peer struct {
+	addr     *net.UDPAddr
+	unacked  map[byte][]byte // pushed messages by sequence
+	seq      byte            // next message sequence
+	loggedIn bool
+}
+
+// NewServer starts a server on a random loopback port that accepts
+// password. It panics if the port cannot be opened.
+func NewServer(password string) *Server {
+	s, err := Listen("127.0.0.1:0", password)
+	if err != nil {
+		panic("bercontest: failed to listen: " + err.Error())
+	}
+
+	return s
+}
+
+// Listen starts a server on addr that accepts password. Listening again on
+// the Addr of a closed server simulates a server restart.
+func Listen(addr, password string) (*Server, error) {
+	ua, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", ua)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		conn:     conn,
+		Addr:     conn.LocalAddr().String(),
+		password: password,
+		pageSize: DefaultPageSize,
+		peers:    make(map[string]*peer),
+		handlers: map[string]Handler{
+			"":        func(string) string { return "" }, // keepalive
+			"players": func(string) string { return Players },
+			"bans":    func(string) string { return Bans },
+			"admins":  func(string) string { return Admins },
+		},
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Close stops the server and waits for its goroutine to exit.
+func (s *Server) Close() error {
+	err := s.conn.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Handle sets the handler for commands whose first word is name
+// (case-insensitive). A nil handler removes it.
+func (s *Server) Handle(name string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name = strings.ToLower(name)
+	if h == nil {
+		delete(s.handlers, name)
+		return
+	}
+
+	s.handlers[name] = h
+}
+
+// Respond makes commands whose first word is name answer with resp.
+func (s *Server) Respond(name, resp string) {
+	s.Handle(name, func(string) string { return resp })
+}
+
+// SetPageSize sets the largest response body per packet; longer responses
+// are sent as multipart. Values <= 0 use DefaultPageSize.
+func (s *Server) SetPageSize(n int) {
+	if n <= 0 {
+		n = DefaultPageSize
+	}
+
+	s.mu.Lock()
+	s.pageSize = n
+	s.mu.Unlock()
+}
+
+// SetReorder makes the server send multipart response pages in reverse
+// order.
+func (s *Server) SetReorder(on bool) {
+	s.mu.Lock()
+	s.reorder = on
+	s.mu.Unlock()
+}
+
+// SetDrop installs a packet loss hook; nil disables loss.
+func (s *Server) SetDrop(fn DropFunc) {
+	s.mu.Lock()
+	s.drop = fn
+	s.mu.Unlock()
+}
+
+// Push sends a server message to every logged-in client and returns the
+// number of clients it was sent to. Messages stay unacknowledged until
+// the client acks them.
+func (s *Server) Push(msg string) int {
+	s.mu.Lock()
+	var out []outgoing
+	for _, p := range s.peers {
+		if !p.loggedIn {
+			continue
+		}
+
+		pkt := bercon.Packet{Kind: bercon.MessagePacket, Seq: p.seq, Data: []byte(msg)}
+		raw := bercon.EncodePacket(pkt)
+		p.unacked[p.seq] = raw
+		p.seq++
+		out = append(out, outgoing{addr: p.addr, pkt: pkt, raw: raw})
+	}
+	s.mu.Unlock()
+
+	s.send(out)
+
+	return len(out)
+}
+
+// ResendUnacked sends every unacknowledged message again, as BattlEye
+// does when it misses an ack, and returns how many were resent.
+func (s *Server) ResendUnacked() int {
+	s.mu.Lock()
+	var out []outgoing
+	for _, p := range s.peers {
+		for _, raw := range p.unacked {
+			pkt, _ := bercon.DecodePacket(raw)
+			out = append(out, outgoing{addr: p.addr, pkt: pkt, raw: raw})
+		}
+	}
+	s.mu.Unlock()
+
+	s.send(out)
+
+	return len(out)
+}
+
+// Unacked returns the number of pushed messages not yet acknowledged.
+func (s *Server) Unacked() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, p := range s.peers {
+		n += len(p.unacked)
+	}
+
+	return n
+}
+
+// WaitAcked waits until all pushed messages are acknowledged and reports
+// whether that happened within timeout.
+func (s *Server) WaitAcked(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for s.Unacked() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return true
+}
+
+// Commands returns the non-empty commands received from logged-in clients
+// in arrival order, including retransmitted copies.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.commands...)
+}
+
+// Logins returns the number of successful and failed login attempts.
+func (s *Server) Logins() (ok, failed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.logins, s.failed
+}
+
+// BadPackets returns the number of received datagrams that failed
+// header or CRC validation.
+func (s *Server) BadPackets() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.bad
+}
+
+// outgoing is a datagram queued for sending.
+type outgoing struct {
+	addr *net.UDPAddr
+	raw  []byte
+	pkt  bercon.Packet
+}
+
+// serve reads and handles client datagrams until the socket is closed.
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	buf := make([]byte, 2048)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		pkt, err := bercon.DecodePacket(buf[:n])
+		if err != nil {
+			s.mu.Lock()
+			s.bad++
+			s.mu.Unlock()
+			continue
+		}
+
+		s.send(s.handle(addr, pkt))
+	}
+}
+
+// handle processes one inbound packet and returns the responses.
+func (s *Server) handle(addr *net.UDPAddr, pkt bercon.Packet) []outgoing {
+	s.mu.Lock()
+
+	if s.drop != nil && s.drop(Inbound, pkt) {
+		s.mu.Unlock()
+		return nil
+	}
+
+	key := addr.String()
+	p := s.peers[key]
+	if p == nil {
+		p = &peer{addr: addr, unacked: make(map[byte][]byte)}
+		s.peers[key] = p
+	}
+
+	switch pkt.Kind {
+	case bercon.LoginPacket:
+		result := byte(0x00)
+		if string(pkt.Data) == s.password || s.replay != nil {
+			result = 0x01
+			s.logins++
+			p.loggedIn = true
+		} else {
+			s.failed++
+			p.loggedIn = false
+		}
+
+		resp := bercon.Packet{Kind: bercon.LoginPacket, Data: []byte{result}}
+		out := []outgoing{{addr: addr, pkt: resp, raw: bercon.EncodePacket(resp)}}
+		if p.loggedIn && s.replay != nil && !s.replay[0].used {
+			s.replay[0].used = true
+			out = append(out, s.replayPackets(p, s.replay[0], 0)...)
+		}
+		s.mu.Unlock()
+
+		return out
+
+	case bercon.MessagePacket:
+		delete(p.unacked, pkt.Seq)
+		s.mu.Unlock()
+		return nil
+
+	case bercon.CommandPacket:
+		if !p.loggedIn {
+			s.mu.Unlock()
+			return nil
+		}
+
+		line := string(pkt.Data)
+		if line != "" {
+			s.commands = append(s.commands, line)
+		}
+
+		if out, ok := s.replayFor(p, line, pkt.Seq); ok {
+			s.mu.Unlock()
+			return out
+		}
+
+		name, _, _ := strings.Cut(line, " ")
+		h := s.handlers[strings.ToLower(name)]
+		pageSize, reorder := s.pageSize, s.reorder
+		s.mu.Unlock()
+
+		resp := UnknownCommand
+		if h != nil {
+			resp = h(line)
+		}
+
+		return paginate(addr, pkt.Seq, resp, pageSize, reorder)
+
+	default:
+		s.mu.Unlock()
+		return nil
+	}
+}
+
+// paginate builds response packets, splitting resp into multipart pages
+// when it exceeds pageSize.
+func paginate(addr *net.UDPAddr, seq byte, resp string, pageSize int, reorder bool) []outgoing {
+	pkts := bercon.SplitResponse(seq, []byte(resp), pageSize)
+
+	out := make([]outgoing, 0, len(pkts))
+	for _, pkt := range pkts {
+		out = append(out, outgoing{addr: addr, pkt: pkt, raw: bercon.EncodePacket(pkt)})
+	}
+
+	if reorder {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+
+	return out
+}
+
+// send writes datagrams that survive the drop hook.
+func (s *Server) send(out []outgoing) {
+	s.mu.Lock()
+	drop := s.drop
+	s.mu.Unlock()
+
+	for _, o := range out {
+		if drop != nil && drop(Outbound, o.pkt) {
+			continue
+		}
+
+		_, _ = s.conn.WriteToUDP(o.raw, o.addr)
+	}
+}
@@ -0,0 +1,61 @@
+package bercontest
+
+import (
+	"testing"
+
+	"github.com/woozymasta/bercon-cli/pkg/beparser"
+)
+
+func TestFixturesParse(t *testing.T) {
+	players := beparser.NewPlayers()
+	players.Parse([]byte(Players))
+	if len(*players) != 4 {
+		t.Fatalf("got %d players, want 4", len(*players))
+	}
+
+	var lobby, invalid int
+	for _, p := range *players {
+		if p.Lobby {
+			lobby++
+		}
+		if !p.Valid {
+			invalid++
+		}
+	}
+	if lobby != 1 || invalid != 1 {
+		t.Fatalf("got %d in lobby and %d invalid, want 1 and 1", lobby, invalid)
+	}
+
+	bans := beparser.NewBans()
+	bans.Parse([]byte(Bans))
+	if len(bans.GUIDBans) != 2 || len(bans.IPBans) != 2 {
+		t.Fatalf("got %d GUID and %d IP bans, want 2 and 2", len(bans.GUIDBans), len(bans.IPBans))
+	}
+
+	admins := beparser.NewAdmins()
+	admins.Parse([]byte(Admins))
+	if len(*admins) != 2 {
+		t.Fatalf("got %d admins, want 2", len(*admins))
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	out := paginate(nil, 9, "abcdefghij", 4, true)
+	if len(out) != 3 {
+		t.Fatalf("got %d pages, want 3", len(out))
+	}
+
+	var joined []byte
+	for i := len(out) - 1; i >= 0; i-- {
+		if out[i].pkt.Pages != 3 || out[i].pkt.Seq != 9 {
+			t.Fatalf("bad page header: %+v", out[i].pkt)
+		}
+		joined = append(joined, out[i].pkt.Data...)
+	}
+	if string(joined) != "abcdefghij" {
+		t.Fatalf("got %q", joined)
+	}
+	if out[0].pkt.Page != 2 {
+		t.Fatalf("reorder: first page sent is %d, want 2", out[0].pkt.Page)
+	}
+}
@@ -2,6 +2,8 @@ package bercon
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -17,11 +19,8 @@ const (
 	// DefaultDeadlineTimeout is the default request/response deadline in seconds.
 	DefaultDeadlineTimeout = 5
 
-	// DefaultMicroSleepTimeout is the default sleep (milliseconds) used in
-	// the tight loop that waits for a free sequence number under load.
-	// Higher values reduce CPU at the cost of a tiny extra latency per
-	// contended Send(); lower values improve responsiveness but may spin more.
-	// Recommended range: 0–2ms. See SetMicroSleepTimeout for details.
+	// DefaultMicroSleepTimeout is the default pause (milliseconds) between
+	// login attempts after a read timeout. See SetMicroSleepTimeout.
 	DefaultMicroSleepTimeout = 1
 
 	// DefaultBufferSize is the max body size accepted in a single UDP read.
@@ -40,13 +39,23 @@ const (
 	// MaxKeepaliveTimeout is the maximum keepalive interval in seconds.
 	// BattlEye tends to drop idle sessions above this value.
 	MaxKeepaliveTimeout = 45
+
+	// minReapInterval bounds how often expired in-flight requests are checked.
+	minReapInterval = 100 * time.Millisecond
+
+	// messageDedupWindow is how long a message sequence number is remembered
+	// to detect packets that BattlEye resends when it missed our ack.
+	messageDedupWindow = 60 * time.Second
+
+	// maxPending caps requests parked while all 256 sequence numbers are busy.
+	maxPending = 1024
 )
 
 // Timeouts defines various timeout configurations for the connection.
 type Timeouts struct {
 	keepalive     time.Duration // interval for sending keepalive packets
 	deadline      time.Duration // maximum time to wait for a response
-	microSleep    time.Duration // sleep duration during busy-wait loops
+	microSleep    time.Duration // pause between login attempts
 	loginAttempts int           // number of login retries
 }
 
@@ -66,15 +75,50 @@ type Connection struct {
 	cancel context.CancelFunc
 
 	// owned by manager loop
-	conn     *net.UDPConn
-	inflight map[byte]*inflight
-	reqCh    chan sendReq // requests from Send()
-	pktCh    chan *packet // parsed packets from reader
-	ackCh    chan byte    // message seq to ack
-	msgCh    chan *packet // internal channel for message dispatch
+	conn        net.Conn
+	dial        DialFunc     // nil when the transport was injected
+	logger      *slog.Logger // nil disables logging
+	tracer      *tracer      // nil disables packet tracing
+	localAddr   string       // local bind address for the default dialer
+	inflight    map[byte]*inflight
+	quarantine  map[byte]time.Time // sequences that may still get late responses
+	pending     []sendReq          // requests waiting for a free sequence number
+	reconnect   *ReconnectPolicy   // nil when automatic reconnect is disabled
+	retransmit  *RetransmitPolicy  // nil when retransmission is disabled
+	seenMsgs    *[256]seenMessage  // recently received message packets by seq
+	lastCommand time.Time          // last command dispatch, for idle detection
+	reqCh       chan sendReq       // requests from Send()
+	abortCh     chan chan sendResp // requests abandoned by their callers
+	pktCh       chan *Packet       // parsed packets from reader
+	ackCh       chan byte          // message seq to ack
+	msgCh       chan *Packet       // internal channel for message dispatch
+	dispatched  chan struct{}      // closed when dispatchLoop exits
+	lostCh      chan error         // fatal session errors from reader
+
+	// statistics
+	stats counters
+	rtt   rttRing
+
+	// lifecycle state and hooks
+	stateHooks  []StateHook
+	stateQueue  []stateChange
+	stateSignal chan struct{}
+	stateMu     sync.Mutex
+	state       State
+
+	// terminal error, set once the connection gave up
+	err   error
+	errMu sync.Mutex
+
+	// guards swapping conn on reconnect against Close
+	connMu sync.Mutex
 
 	// outward events
+	// Messages is the default subscriber (DropNewest policy, buffer of
+	// DefaultMessagesBufferSize); use Subscribe for more consumers.
 	Messages chan PacketEvent
+	subs     []*Subscription // copy-on-write, guarded by subsMu
+	subsMu   sync.RWMutex
 
 	// immutable after Open
 	address  string
@@ -84,17 +128,26 @@ type Connection struct {
 	timeouts     Timeouts
 	wg           sync.WaitGroup
 	lastActivity int64 // atomic unix nano
+	lastRecv     int64 // atomic unix nano, last packet received from server
 	close        sync.Once
 
 	alive      uint32 // 1 if active
+	subsClosed bool   // guarded by subsMu
 	bufferSize uint16
 
 	sequence  byte
 	keepalive bool
 }
 
+// seenMessage remembers a received message packet for de-duplication.
+type seenMessage struct {
+	at  time.Time
+	sum uint32 // CRC32 of the payload
+}
+
 // internal plumbing for Send()
 type sendReq struct {
+	ts      time.Time
 	respCh  chan sendResp
 	command string
 }
@@ -106,28 +159,103 @@ type sendResp struct {
 
 // single in-flight response aggregator (for multipart)
 type inflight struct {
-	ts    time.Time
-	done  chan sendResp
-	data  []byte
-	pages byte
-	page  byte
+	ts       time.Time // first transmission
+	sent     time.Time // last transmission
+	done     chan sendResp
+	cmd      []byte   // command body, kept for retransmission
+	parts    [][]byte // multipart pages by index, nil until received
+	sends    int      // number of transmissions
+	received int      // number of distinct pages received
+	pages    byte
 }
 
 // Open initializes and returns a new Connection to the specified BattlEye server using the provided address and password.
+// Use OpenWithOptions to configure the connection before login.
 func Open(addr, pass string) (*Connection, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", addr)
-	if err != nil {
+	return OpenContext(context.Background(), addr, pass)
+}
+
+// OpenContext is like Open but aborts dialing and the login handshake when
+// ctx is cancelled or its deadline expires (whichever comes first with the
+// default deadline). ctx only bounds opening; use Close to end the session.
+func OpenContext(ctx context.Context, addr, pass string) (*Connection, error) {
+	return open(ctx, addr, pass, nil)
+}
+
+// OpenWithOptions is like Open but applies opts before the transport is
+// dialed and the login handshake starts.
+func OpenWithOptions(addr, pass string, opts ...Option) (*Connection, error) {
+	return open(context.Background(), addr, pass, opts)
+}
+
+// OpenWithDialer is like OpenContext but opens the transport with dial,
+// which is also used for every automatic reconnect. A nil dial uses a
+// connected UDP socket.
+func OpenWithDialer(ctx context.Context, addr, pass string, dial DialFunc) (*Connection, error) {
+	return open(ctx, addr, pass, []Option{WithDialer(dial)})
+}
+
+// OpenWithConn logs in over an already established transport to a
+// BattlEye server. Each Read and Write on conn must carry exactly one
+// datagram. The Connection takes ownership of conn and closes it on Close.
+// Without a WithDialer option it has no way to re-establish conn, so a lost
+// session stops the connection even when reconnect is enabled.
+func OpenWithConn(conn net.Conn, pass string, opts ...Option) (*Connection, error) {
+	if conn == nil {
+		return nil, ErrConnectionClosed
+	}
+
+	var addr string
+	if ra := conn.RemoteAddr(); ra != nil {
+		addr = ra.String()
+	}
+
+	c := newConnection(addr, pass, opts)
+	c.conn = conn
+	if err := c.start(context.Background()); err != nil {
 		return nil, err
 	}
-	rawConn, err := net.DialUDP("udp", nil, udpAddr)
+
+	return c, nil
+}
+
+// OpenWithPacketConn is like OpenWithConn for an unconnected packet
+// transport: datagrams are written to addr and datagrams received from
+// other peers are ignored.
+func OpenWithPacketConn(pc net.PacketConn, addr net.Addr, pass string, opts ...Option) (*Connection, error) {
+	if pc == nil || addr == nil {
+		return nil, ErrConnectionClosed
+	}
+
+	return OpenWithConn(&packetConn{PacketConn: pc, raddr: addr}, pass, opts...)
+}
+
+// open builds a Connection with opts, dials addr and logs in.
+func open(ctx context.Context, addr, pass string, opts []Option) (*Connection, error) {
+	c := newConnection(addr, pass, opts)
+	if c.dial == nil {
+		c.dial = c.dialUDP
+	}
+
+	rawConn, err := c.dial(ctx, addr)
 	if err != nil {
+		c.cancel()
 		return nil, err
 	}
 
+	c.conn = rawConn
+	if err := c.start(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newConnection builds a Connection with default settings and applies opts.
+func newConnection(addr, pass string, opts []Option) *Connection {
 	c := &Connection{
 		address:    addr,
 		password:   pass,
-		conn:       rawConn,
 		bufferSize: DefaultBufferSize + DefaultBufferHeaderSize,
 		timeouts: Timeouts{
 			keepalive:     DefaultKeepaliveTimeout * time.Second,
@@ -135,37 +263,66 @@ func Open(addr, pass string) (*Connection, error) {
 			microSleep:    DefaultMicroSleepTimeout * time.Millisecond,
 			loginAttempts: DefaultLoginAttempts,
 		},
-		Messages: make(chan PacketEvent, 32),
-
-		reqCh:    make(chan sendReq, 4),
-		pktCh:    make(chan *packet, 64),
-		ackCh:    make(chan byte, 64),
-		msgCh:    make(chan *packet, 64),
-		inflight: make(map[byte]*inflight, 16),
+		Messages: make(chan PacketEvent, DefaultMessagesBufferSize),
+
+		reqCh:      make(chan sendReq, 4),
+		abortCh:    make(chan chan sendResp, 64),
+		pktCh:      make(chan *Packet, 64),
+		ackCh:      make(chan byte, 64),
+		msgCh:      make(chan *Packet, 64),
+		dispatched: make(chan struct{}),
+		lostCh:     make(chan error, 1),
+		inflight:   make(map[byte]*inflight, 16),
+		quarantine: make(map[byte]time.Time),
+		seenMsgs:   new([256]seenMessage),
+
+		stateSignal: make(chan struct{}, 1),
 	}
 
 	atomic.StoreInt64(&c.lastActivity, time.Now().UnixNano())
+	atomic.StoreInt64(&c.lastRecv, time.Now().UnixNano())
 
 	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.subscribe(c.Messages, nil, DropNewest)
 
-	// login synchronously before loops
-	if err := c.loginOnce(); err != nil {
-		_ = rawConn.Close()
-		return nil, err
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+
+	return c
+}
+
+// start logs in synchronously and then starts the connection loops.
+// On failure the transport is closed.
+func (c *Connection) start(ctx context.Context) error {
+	go c.stateLoop()
+
+	if err := c.loginOnce(ctx); err != nil {
+		c.log().Warn("login failed", "addr", c.address, "err", err)
+		c.cancel()
+		c.setState(StateClosed, err)
+		_ = c.conn.Close()
+		return err
 	}
 
+	c.log().Debug("logged in", "addr", c.address)
 	atomic.StoreUint32(&c.alive, 1)
+	c.lastCommand = time.Now()
+	c.setState(StateLoggedIn, nil)
 
 	// start reader, manager and dispatcher
 	c.wg.Add(3)
-	go c.readerLoop()
+	go c.readerLoop(c.conn)
 	go c.managerLoop()
 	go c.dispatchLoop()
 
-	return c, nil
+	return nil
 }
 
 // SetBufferSize updates the buffer size for receiving packets from the server.
+// The reader allocates its buffer at Open, so use WithBufferSize instead.
 func (c *Connection) SetBufferSize(size uint16) {
 	cumulative := uint16(MaxCommandBodySize + DefaultBufferHeaderSize)
 	if size > cumulative {
@@ -233,7 +390,8 @@ func (c *Connection) SetDeadlineTimeout(seconds int) {
 	c.timeouts.deadline = time.Duration(seconds) * time.Second
 }
 
-// SetLoginAttempts sets the number of login attempts.
+// SetLoginAttempts sets the number of login attempts. After Open it only
+// affects re-login on reconnect; use WithLoginAttempts for the first login.
 func (c *Connection) SetLoginAttempts(attempts int) {
 	if attempts < 1 {
 		attempts = DefaultLoginAttempts
@@ -242,15 +400,15 @@ func (c *Connection) SetLoginAttempts(attempts int) {
 	c.timeouts.loginAttempts = attempts
 }
 
-// MicroSleep returns the current micro-sleep interval used in the tight loop
-// when searching for a free sequence number under contention. Zero means
-// sleeping is disabled (busy-spin).
+// MicroSleep returns the current pause between login attempts after a read
+// timeout. Zero means no pause. Sequence allocation never sleeps: requests
+// without a free sequence number are parked until a slot is released.
 func (c *Connection) MicroSleep() time.Duration {
 	return c.timeouts.microSleep
 }
 
-// SetMicroSleep sets the micro-sleep interval used while waiting for a free
-// sequence slot under contention. 0 disables sleeping (max responsiveness).
+// SetMicroSleep sets the pause between login attempts after a read timeout.
+// 0 disables sleeping.
 func (c *Connection) SetMicroSleep(d time.Duration) {
 	if d <= 0 {
 		c.timeouts.microSleep = 0
@@ -260,10 +418,8 @@ func (c *Connection) SetMicroSleep(d time.Duration) {
 	c.timeouts.microSleep = d
 }
 
-// SetMicroSleepTimeout adjusts the micro-sleep interval (in ms) used in the
-// loop that searches for a free sequence number when all 0..255 slots are
-// temporarily busy. A value of 0 means no sleeping (max responsiveness,
-// more CPU when heavily contended). Typical sweet spot: 1–2ms.
+// SetMicroSleepTimeout adjusts the pause (in ms) between login attempts
+// after a read timeout. A value of 0 means no sleeping.
 func (c *Connection) SetMicroSleepTimeout(milliseconds int) {
 	if milliseconds <= 0 {
 		c.timeouts.microSleep = 0
@@ -285,24 +441,50 @@ func (c *Connection) IsAlive() bool {
 	return time.Since(time.Unix(0, last)) <= threshold
 }
 
+// Reaped returns how many in-flight requests were dropped without a
+// response: abandoned by their callers or expired after the deadline.
+func (c *Connection) Reaped() uint64 {
+	return atomic.LoadUint64(&c.stats.reaped)
+}
+
+// DuplicateMessages returns how many server message packets were recognized
+// as resent copies, acked again and not delivered to Messages.
+func (c *Connection) DuplicateMessages() uint64 {
+	return atomic.LoadUint64(&c.stats.duplicates)
+}
+
+// Err returns the error that permanently stopped the connection (for example
+// ErrReconnectFailed or ErrReconnectWindow), or nil while it is usable or
+// after a regular Close.
+func (c *Connection) Err() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+
+	return c.err
+}
+
 // Close gracefully closes the connection, releases resources, and ensures no further operations are performed.
+// It is safe to call Close on a connection that was already lost or is
+// reconnecting; Messages and all subscriptions are closed exactly once.
 func (c *Connection) Close() error {
-	if !c.IsAlive() {
-		return nil
-	}
-
 	var err error
 	c.close.Do(func() {
 		atomic.StoreUint32(&c.alive, 0)
 		c.cancel()
 
 		// Force close socket for unlock readerLoop
+		c.connMu.Lock()
 		if c.conn != nil {
 			err = c.conn.Close()
+			if errors.Is(err, net.ErrClosed) {
+				err = nil
+			}
 		}
+		c.connMu.Unlock()
 
 		c.wg.Wait()
-		close(c.Messages)
+		c.closeSubscriptions()
+		c.setState(StateClosed, nil)
 	})
 
 	return err
@@ -315,16 +497,36 @@ func (c *Connection) StartKeepAlive() {
 
 // Send dispatches a command to the BattlEye server and waits for a response.
 func (c *Connection) Send(command string) ([]byte, error) {
+	return c.SendContext(context.Background(), command)
+}
+
+// SendContext dispatches a command and waits for a response, the connection
+// deadline, or ctx to be done, whichever happens first. When ctx ends first,
+// ctx.Err() is returned; ErrTimeout means the connection deadline expired.
+// In both cases the sequence number reserved for the command is released.
+func (c *Connection) SendContext(ctx context.Context, command string) ([]byte, error) {
+	data, err := c.send(ctx, command)
+	c.countCommand(err)
+
+	return data, err
+}
+
+func (c *Connection) send(ctx context.Context, command string) ([]byte, error) {
 	if !c.IsAlive() {
 		return nil, ErrConnectionDown
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// global timer for operation
 	timer := time.NewTimer(c.timeouts.deadline)
 	defer timer.Stop()
 
 	respCh := make(chan sendResp, 1)
 	req := sendReq{
+		ts:      time.Now(),
 		command: command,
 		respCh:  respCh,
 	}
@@ -333,6 +535,9 @@ func (c *Connection) Send(command string) ([]byte, error) {
 	select {
 	case c.reqCh <- req: // succes add to queue
 
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, ErrConnectionClosed
 
@@ -345,10 +550,24 @@ func (c *Connection) Send(command string) ([]byte, error) {
 	case resp := <-respCh:
 		return resp.data, resp.err
 
+	case <-ctx.Done():
+		c.abandon(respCh)
+		return nil, ctx.Err()
+
 	case <-c.ctx.Done():
 		return nil, ErrConnectionClosed
 
 	case <-timer.C:
+		c.abandon(respCh)
 		return nil, ErrTimeout
 	}
 }
+
+// abandon asks managerLoop to forget the request answered on respCh so its
+// sequence number can be reused. It never blocks the caller.
+func (c *Connection) abandon(respCh chan sendResp) {
+	select {
+	case c.abortCh <- respCh:
+	default:
+	}
+}
@@ -0,0 +1,325 @@
+package bercon_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+	"github.com/woozymasta/bercon-cli/pkg/bercon/bercontest"
+)
+
+func TestLoginFailed(t *testing.T) {
+	srv := bercontest.NewServer("secret")
+	defer srv.Close()
+
+	_, err := bercon.Open(srv.Addr, "wrong")
+	if !errors.Is(err, bercon.ErrLoginFailed) {
+		t.Fatalf("got %v, want %v", err, bercon.ErrLoginFailed)
+	}
+
+	if ok, failed := srv.Logins(); ok != 0 || failed != 1 {
+		t.Fatalf("got %d ok and %d failed logins, want 0 and 1", ok, failed)
+	}
+}
+
+func TestSendFixture(t *testing.T) {
+	_, c := bercontest.Open(t, "secret")
+
+	data, err := c.Send("players")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if string(data) != bercontest.Players {
+		t.Fatalf("got %q, want players fixture", data)
+	}
+}
+
+func TestSendMultipartReordered(t *testing.T) {
+	srv, c := bercontest.Open(t, "secret")
+	srv.SetPageSize(64)
+	srv.SetReorder(true)
+
+	want := strings.Repeat("0123456789", 40)
+	srv.Respond("long", want)
+
+	data, err := c.Send("long")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if string(data) != want {
+		t.Fatalf("got %d bytes, want %d", len(data), len(want))
+	}
+}
+
+func TestMessagesAckedOnce(t *testing.T) {
+	srv, c := bercontest.Open(t, "secret")
+
+	if n := srv.Push("(Global) Survivor: hello"); n != 1 {
+		t.Fatalf("pushed to %d clients, want 1", n)
+	}
+
+	select {
+	case ev := <-c.Messages:
+		if string(ev.Data) != "(Global) Survivor: hello" {
+			t.Fatalf("got %q", ev.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	if !srv.WaitAcked(2 * time.Second) {
+		t.Fatal("message not acknowledged")
+	}
+
+	// a resent copy is acked again, but not delivered twice
+	srv.Push("second")
+	<-c.Messages
+	srv.ResendUnacked()
+	if _, err := c.Send("admins"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	select {
+	case ev := <-c.Messages:
+		t.Fatalf("duplicate delivered: %q", ev.Data)
+	default:
+	}
+}
+
+func TestOpenWithOptions_LoginRetry(t *testing.T) {
+	srv := bercontest.NewServer("secret")
+	defer srv.Close()
+
+	lost := false
+	srv.SetDrop(func(dir bercontest.Direction, p bercon.Packet) bool {
+		if dir == bercontest.Inbound && p.Kind == bercon.LoginPacket && !lost {
+			lost = true
+			return true
+		}
+		return false
+	})
+
+	var states []bercon.State
+	closed := make(chan struct{})
+	c, err := bercon.OpenWithOptions(srv.Addr, "secret",
+		bercon.WithDeadline(2*time.Second),
+		bercon.WithLoginAttempts(2),
+		bercon.WithMicroSleep(0),
+		bercon.WithStateHook(func(_, to bercon.State, _ error) {
+			states = append(states, to)
+			if to == bercon.StateClosed {
+				close(closed)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	_ = c.Close()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("closed state not reported")
+	}
+
+	if ok, _ := srv.Logins(); ok != 1 {
+		t.Fatalf("got %d logins, want 1", ok)
+	}
+	if len(states) != 2 || states[0] != bercon.StateLoggedIn || states[1] != bercon.StateClosed {
+		t.Fatalf("got states %v, want [logged-in closed]", states)
+	}
+}
+
+func TestRetransmitLostCommand(t *testing.T) {
+	srv, c := bercontest.Open(t, "secret", bercon.WithRetransmit(bercon.RetransmitPolicy{
+		Interval: 50 * time.Millisecond,
+		Attempts: 3,
+	}))
+	srv.SetDrop(bercontest.DropCommand("bans", 1))
+
+	data, err := c.Send("bans")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if string(data) != bercontest.Bans {
+		t.Fatalf("got %q, want bans fixture", data)
+	}
+
+	if got := c.Retransmits(); got == 0 {
+		t.Fatal("no retransmission counted")
+	}
+}
+
+func TestReconnectAfterRestart(t *testing.T) {
+	srv, c := bercontest.Open(t, "secret",
+		bercon.WithKeepalive(time.Second),
+		bercon.WithDeadline(time.Second),
+		bercon.WithReconnect(bercon.ReconnectPolicy{
+			Backoff:   50 * time.Millisecond,
+			MaxWindow: 10 * time.Second,
+		}),
+	)
+
+	addr := srv.Addr
+	_ = srv.Close()
+
+	next, err := bercontest.Listen(addr, "secret")
+	if err != nil {
+		t.Skipf("cannot listen on %s again: %v", addr, err)
+	}
+	defer next.Close()
+
+	deadline := time.Now().Add(8 * time.Second)
+	for time.Now().Before(deadline) {
+		if ok, _ := next.Logins(); ok > 0 && c.State() == bercon.StateLoggedIn {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, err := c.Send("players"); err != nil {
+		t.Fatalf("send after restart: %v", err)
+	}
+	if c.Stats().Reconnects == 0 {
+		t.Fatal("no reconnect counted")
+	}
+}
+
+func TestReconnectGiveUpClosesSubscriptions(t *testing.T) {
+	srv, c := bercontest.Open(t, "secret",
+		bercon.WithKeepalive(200*time.Millisecond),
+		bercon.WithDeadline(200*time.Millisecond),
+		bercon.WithReconnect(bercon.ReconnectPolicy{
+			Backoff:     20 * time.Millisecond,
+			MaxAttempts: 2,
+		}),
+	)
+	sub := c.Subscribe(nil, 1, bercon.DropNewest)
+
+	_ = srv.Close()
+
+	timeout := time.After(5 * time.Second)
+	for _, ch := range []<-chan bercon.PacketEvent{sub.C, c.Messages} {
+	drain:
+		for {
+			select {
+			case _, ok := <-ch:
+				if !ok {
+					break drain
+				}
+			case <-timeout:
+				t.Fatalf("channel still open, state %s", c.State())
+			}
+		}
+	}
+
+	if !errors.Is(c.Err(), bercon.ErrReconnectFailed) {
+		t.Fatalf("got err %v, want %v", c.Err(), bercon.ErrReconnectFailed)
+	}
+	if c.State() != bercon.StateClosed {
+		t.Fatalf("got state %s, want closed", c.State())
+	}
+}
+
+func TestLoggerRecords(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	srv := bercontest.NewServer("secret")
+	defer srv.Close()
+	srv.SetDrop(bercontest.DropCommand("players", 1))
+
+	c, err := bercon.OpenWithOptions(srv.Addr, "secret",
+		bercon.WithLogger(logger),
+		bercon.WithRetransmit(bercon.RetransmitPolicy{Interval: 50 * time.Millisecond, Attempts: 2}),
+	)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	if _, err := c.Send("players"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	_ = c.Close()
+
+	out := buf.String()
+	for _, want := range []string{
+		"msg=\"logged in\"",
+		"msg=\"command retransmitted\" seq=0 sends=2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("log output lacks %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestSendContextCancel(t *testing.T) {
+	srv, c := bercontest.Open(t, "secret", bercon.WithDeadline(5*time.Second))
+	srv.SetDrop(bercontest.DropCommand("bans", 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	if _, err := c.SendContext(ctx, "bans"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("send returned after %s, want on cancel", d)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().InFlight > 0 || c.Reaped() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("slot not released: %d in flight, %d reaped", c.Stats().InFlight, c.Reaped())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	data, err := c.Send("bans")
+	if err != nil {
+		t.Fatalf("send after cancel: %v", err)
+	}
+	if string(data) != bercontest.Bans {
+		t.Fatalf("got %q, want bans fixture", data)
+	}
+}
+
+func TestSendConcurrentOverSequenceSpace(t *testing.T) {
+	_, c := bercontest.Open(t, "secret", bercon.WithDeadline(10*time.Second))
+
+	// more callers than the 256 sequence numbers; the rest must be parked
+	// and sent as slots free up
+	const callers = 300
+	errs := make(chan error, callers)
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Go(func() {
+			data, err := c.Send("players")
+			if err == nil && string(data) != bercontest.Players {
+				err = fmt.Errorf("got %q, want players fixture", data)
+			}
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if st := c.Stats(); st.CommandsOK != callers || st.InFlight != 0 || st.Pending != 0 {
+		t.Fatalf("got %d ok, %d in flight, %d pending; want %d, 0, 0",
+			st.CommandsOK, st.InFlight, st.Pending, callers)
+	}
+}
@@ -0,0 +1,15 @@
+package bercon
+
+import "log/slog"
+
+// discardLogger is used when no logger was configured.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// log returns the configured logger or a logger that discards records.
+func (c *Connection) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+
+	return c.logger
+}
@@ -1,7 +1,9 @@
 package bercon
 
 import (
+	"context"
 	"errors"
+	"hash/crc32"
 	"net"
 	"sync/atomic"
 	"time"
@@ -12,70 +14,109 @@ func (c *Connection) managerLoop() {
 	defer c.wg.Done()
 	tk := time.NewTicker(c.timeouts.keepalive)
 	defer tk.Stop()
+	housekeeping := time.NewTimer(c.housekeepingInterval())
+	defer housekeeping.Stop()
 
 	for {
+		c.syncQueueStats()
+
 		select {
 		case <-c.ctx.Done():
 			return
 
+		case err := <-c.lostCh:
+			if !c.recoverSession(err) {
+				return
+			}
+
 		case req := <-c.reqCh:
-			seq, ok := c.nextFreeSeq(c.timeouts.deadline)
-			if !ok {
+			if len(c.pending) >= maxPending {
+				c.log().Warn("send queue full", "pending", len(c.pending))
 				req.respCh <- sendResp{data: nil, err: ErrBufferFull}
 				continue
 			}
 
-			holder := &inflight{done: req.respCh, ts: time.Now()}
-			c.inflight[seq] = holder
+			c.pending = append(c.pending, req)
+			c.dispatchPending()
 
-			if err := c.writePacket(commandPacket, []byte(req.command), seq); err != nil {
-				delete(c.inflight, seq)
-				req.respCh <- sendResp{data: nil, err: err}
-				continue
-			}
+		case respCh := <-c.abortCh:
+			c.release(respCh)
+			c.dispatchPending()
 
 		case pkt := <-c.pktCh:
-			switch pkt.kind {
-			case loginPacket:
-				select {
-				case c.msgCh <- pkt:
-				default:
-				}
-
-			case messagePacket:
-				select {
-				case c.msgCh <- pkt:
-				default:
+			switch pkt.Kind {
+			case LoginPacket:
+				c.emit(pkt)
+
+			case MessagePacket:
+				if c.duplicateMessage(pkt, time.Now()) {
+					atomic.AddUint64(&c.stats.duplicates, 1)
+					c.log().Debug("duplicate message", "seq", pkt.Seq)
+				} else {
+					c.emit(pkt)
 				}
 
 				// ack will be sent by manager
 				select {
-				case c.ackCh <- pkt.seq:
+				case c.ackCh <- pkt.Seq:
 				default:
+					c.log().Warn("ack queue full, message not acked", "seq", pkt.Seq)
 				}
 
-			case commandPacket:
+			case CommandPacket:
 				c.handleCommandPacket(pkt)
+				c.dispatchPending()
 			}
 
+		case now := <-housekeeping.C:
+			c.retransmitInflight(now)
+			c.reapInflight(now)
+			c.dispatchPending()
+			c.updateIdle(now)
+			housekeeping.Reset(c.housekeepingInterval())
+
 		case seq := <-c.ackCh:
-			_ = c.writePacket(messagePacket, nil, seq)
+			if err := c.writePacket(MessagePacket, nil, seq); err != nil {
+				c.log().Warn("ack write failed", "seq", seq, "err", err)
+			}
 
 		case <-tk.C:
+			if c.reconnect != nil && c.keepalive && c.silent() {
+				if !c.recoverSession(ErrNotResponse) {
+					return
+				}
+				continue
+			}
+
 			if c.keepalive {
 				// fire-and-forget empty command to keep login alive.
 				if seq, ok := c.tryFindFreeSeq(); ok {
-					_ = c.writePacket(commandPacket, nil, seq)
+					if err := c.writePacket(CommandPacket, nil, seq); err != nil {
+						c.log().Warn("keepalive write failed", "seq", seq, "err", err)
+					}
 				}
 			}
 		}
 	}
 }
 
+// emit queues a login or message packet for dispatchLoop, dropping it
+// when the dispatcher is behind.
+// NOTE: must be called only from managerLoop.
+func (c *Connection) emit(pkt *Packet) {
+	select {
+	case c.msgCh <- pkt:
+	default:
+		atomic.AddUint64(&c.stats.messagesDropped, 1)
+		c.log().Warn("message dropped, dispatch queue full", "kind", pkt.Kind, "seq", pkt.Seq)
+	}
+}
+
 func (c *Connection) tryFindFreeSeq() (byte, bool) {
+	now := time.Now()
 	for i := 0; i < 256; i++ {
 		s := c.sequence
-		if _, busy := c.inflight[s]; !busy {
+		if !c.seqBusy(s, now) {
 			c.sequence++
 			return s, true
 		}
@@ -85,63 +126,236 @@ func (c *Connection) tryFindFreeSeq() (byte, bool) {
 	return 0, false
 }
 
-// nextFreeSeq finds next sequence id that is not currently inflight.
-func (c *Connection) nextFreeSeq(deadline time.Duration) (byte, bool) {
-	exp := time.Now().Add(deadline)
-	for {
-		seq := c.sequence
-		if _, busy := c.inflight[seq]; !busy {
-			c.sequence++
-			return seq, true
+// seqBusy reports whether s is in flight or still quarantined.
+func (c *Connection) seqBusy(s byte, now time.Time) bool {
+	if _, busy := c.inflight[s]; busy {
+		return true
+	}
+
+	if until, ok := c.quarantine[s]; ok {
+		if now.Before(until) {
+			return true
+		}
+		delete(c.quarantine, s)
+	}
+
+	return false
+}
+
+// retire removes an in-flight entry. A sequence number that may still get
+// a late or duplicated response (unanswered or retransmitted command) is
+// quarantined for one deadline, so that response cannot be mistaken for
+// the answer to a newer command reusing the same number.
+func (c *Connection) retire(seq byte, holder *inflight, answered bool) {
+	delete(c.inflight, seq)
+
+	if !answered || holder.sends > 1 {
+		c.quarantine[seq] = time.Now().Add(c.timeouts.deadline)
+	}
+}
+
+// dispatchPending sends queued requests in FIFO order while free sequence
+// numbers are available. Requests that do not fit stay parked until a
+// response, an abort or the reaper releases a slot.
+func (c *Connection) dispatchPending() {
+	for len(c.pending) > 0 {
+		seq, ok := c.tryFindFreeSeq()
+		if !ok {
+			return
+		}
+
+		req := c.pending[0]
+		c.pending[0] = sendReq{}
+		c.pending = c.pending[1:]
+
+		now := time.Now()
+		c.lastCommand = now
+		c.updateIdle(now)
+
+		holder := &inflight{
+			done:  req.respCh,
+			ts:    now,
+			sent:  now,
+			cmd:   []byte(req.command),
+			sends: 1,
+		}
+		c.inflight[seq] = holder
+
+		if err := c.writePacket(CommandPacket, holder.cmd, seq); err != nil {
+			c.log().Warn("command write failed", "seq", seq, "err", err)
+			delete(c.inflight, seq)
+			req.respCh <- sendResp{data: nil, err: err}
+		}
+	}
+}
+
+// reapInflight expires in-flight requests older than the deadline. Their
+// callers have already given up, but a lost response would otherwise keep
+// the sequence number busy forever and eventually exhaust the 0..255 ring.
+func (c *Connection) reapInflight(now time.Time) {
+	for seq, holder := range c.inflight {
+		if now.Sub(holder.ts) <= c.timeouts.deadline {
+			continue
+		}
+
+		c.retire(seq, holder, false)
+		atomic.AddUint64(&c.stats.reaped, 1)
+		c.log().Debug("request expired without response", "seq", seq, "sends", holder.sends)
+
+		// waiter normally left already; never block on it
+		select {
+		case holder.done <- sendResp{data: nil, err: ErrTimeout}:
+		default:
 		}
+	}
 
-		if time.Now().After(exp) {
-			return 0, false
+	// parked requests whose callers surely gave up
+	kept := c.pending[:0]
+	for _, req := range c.pending {
+		if now.Sub(req.ts) <= c.timeouts.deadline {
+			kept = append(kept, req)
+			continue
 		}
 
-		time.Sleep(c.timeouts.microSleep)
+		atomic.AddUint64(&c.stats.reaped, 1)
+		select {
+		case req.respCh <- sendResp{data: nil, err: ErrTimeout}:
+		default:
+		}
 	}
+	clear(c.pending[len(kept):])
+	c.pending = kept
+}
+
+// duplicateMessage reports whether pkt repeats a message packet already
+// seen recently. BattlEye resends a message until it gets an ack, so a copy
+// with the same sequence number and payload is acked again but not emitted.
+func (c *Connection) duplicateMessage(pkt *Packet, now time.Time) bool {
+	sum := crc32.ChecksumIEEE(pkt.Data)
+	seen := &c.seenMsgs[pkt.Seq]
+
+	if !seen.at.IsZero() && seen.sum == sum && now.Sub(seen.at) <= messageDedupWindow {
+		return true
+	}
+
+	seen.at = now
+	seen.sum = sum
+
+	return false
 }
 
-// assemble multipart or complete single-part and reply to waiter
-func (c *Connection) handleCommandPacket(pkt *packet) {
-	holder, ok := c.inflight[pkt.seq]
+// release drops the in-flight or parked request answered on respCh, if any.
+func (c *Connection) release(respCh chan sendResp) {
+	for seq, holder := range c.inflight {
+		if holder.done == respCh {
+			c.retire(seq, holder, false)
+			atomic.AddUint64(&c.stats.reaped, 1)
+			c.log().Debug("request abandoned by caller", "seq", seq)
+			return
+		}
+	}
+
+	for i, req := range c.pending {
+		if req.respCh == respCh {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			atomic.AddUint64(&c.stats.reaped, 1)
+			return
+		}
+	}
+}
+
+// assemble multipart or complete single-part and reply to waiter.
+// Multipart pages may arrive in any order and more than once (UDP gives no
+// ordering guarantee, retransmission causes duplicates); they are buffered
+// by index and joined once every page is present. A missing page leaves
+// the request in flight until the deadline.
+func (c *Connection) handleCommandPacket(pkt *Packet) {
+	holder, ok := c.inflight[pkt.Seq]
 	if !ok {
+		if len(pkt.Data) > 0 || pkt.Pages != 0 {
+			c.log().Debug("response without request dropped", "seq", pkt.Seq)
+		}
 		return // stale/keepalive response; drop
 	}
 
 	// single-part
-	if holder.pages == 0 && pkt.pages == 0 {
-		delete(c.inflight, pkt.seq)
-		holder.done <- sendResp{data: pkt.data, err: nil}
+	if pkt.Pages == 0 {
+		if holder.pages != 0 {
+			c.log().Warn("single-part response to multipart request", "seq", pkt.Seq)
+			c.retire(pkt.Seq, holder, false)
+			holder.done <- sendResp{data: nil, err: ErrBadPart}
+			return
+		}
+
+		c.sampleRTT(holder)
+		c.retire(pkt.Seq, holder, true)
+		holder.done <- sendResp{data: pkt.Data, err: nil}
+		return
+	}
+
+	if pkt.Page >= pkt.Pages {
+		c.log().Warn("multipart page out of range", "seq", pkt.Seq, "page", pkt.Page, "pages", pkt.Pages)
+		c.retire(pkt.Seq, holder, false)
+		holder.done <- sendResp{data: nil, err: ErrBadSequence}
 		return
 	}
 
 	// multipart assemble
 	if holder.pages == 0 {
-		holder.pages = pkt.pages
-		holder.page = pkt.page
-		holder.data = append(holder.data, pkt.data...)
-	} else {
-		if holder.page+1 != pkt.page {
-			delete(c.inflight, pkt.seq)
-			holder.done <- sendResp{data: nil, err: ErrBadSequence}
-			return
-		}
+		holder.pages = pkt.Pages
+		holder.parts = make([][]byte, pkt.Pages)
+	} else if holder.pages != pkt.Pages {
+		c.log().Warn("multipart page count changed", "seq", pkt.Seq, "pages", pkt.Pages, "want", holder.pages)
+		c.retire(pkt.Seq, holder, false)
+		holder.done <- sendResp{data: nil, err: ErrBadPart}
+		return
+	}
 
-		holder.page = pkt.page
-		holder.data = append(holder.data, pkt.data...)
+	// page repeated by the server or a retransmitted command
+	if holder.parts[pkt.Page] != nil {
+		c.log().Debug("duplicate multipart page", "seq", pkt.Seq, "page", pkt.Page)
+		return
 	}
 
-	if holder.pages == holder.page+1 {
-		delete(c.inflight, pkt.seq)
-		holder.done <- sendResp{data: holder.data, err: nil}
+	part := pkt.Data
+	if part == nil {
+		part = []byte{}
 	}
+	holder.parts[pkt.Page] = part
+	holder.received++
+
+	if holder.received < int(holder.pages) {
+		return
+	}
+
+	size := 0
+	for _, p := range holder.parts {
+		size += len(p)
+	}
+
+	data := make([]byte, 0, size)
+	for _, p := range holder.parts {
+		data = append(data, p...)
+	}
+
+	c.sampleRTT(holder)
+	c.retire(pkt.Seq, holder, true)
+	holder.done <- sendResp{data: data, err: nil}
 }
 
-// dispatchLoop handles buffering and sending events to the user
+// sampleRTT records the round trip of an answered command. Retransmitted
+// commands are skipped, as the response cannot be matched to one of the
+// transmissions (Karn's algorithm).
+func (c *Connection) sampleRTT(holder *inflight) {
+	if holder.sends == 1 {
+		c.rtt.add(time.Since(holder.ts))
+	}
+}
+
+// dispatchLoop handles buffering and fans events out to subscribers
 func (c *Connection) dispatchLoop() {
 	defer c.wg.Done()
+	defer close(c.dispatched)
 
 	for {
 		select {
@@ -149,30 +363,26 @@ func (c *Connection) dispatchLoop() {
 			return
 
 		case pkt := <-c.msgCh:
-			select {
-			case c.Messages <- PacketEvent{Time: time.Now(), Data: pkt.data, Seq: pkt.seq}:
-
-			case <-c.ctx.Done():
-				return
-			}
+			c.publish(PacketEvent{Time: time.Now(), Data: pkt.Data, Seq: pkt.Seq})
 		}
 	}
 }
 
 // readerLoop reads UDP, parses packets and forwards to manager.
-func (c *Connection) readerLoop() {
+// Each session (initial login and every reconnect) runs its own reader
+// bound to the socket of that session.
+func (c *Connection) readerLoop(conn net.Conn) {
 	defer c.wg.Done()
-	defer c.cancel()
 
 	buf := make([]byte, c.bufferSize)
 
 	for {
-		if c.conn == nil {
+		if conn == nil {
 			return
 		}
 
-		_ = c.conn.SetReadDeadline(time.Now().Add(c.timeouts.deadline))
-		n, err := c.conn.Read(buf)
+		_ = conn.SetReadDeadline(time.Now().Add(c.timeouts.deadline))
+		n, err := conn.Read(buf)
 		if err != nil {
 			// normalize close/timeouts
 			if errors.Is(err, net.ErrClosed) || c.ctx.Err() != nil {
@@ -183,21 +393,31 @@ func (c *Connection) readerLoop() {
 				continue // deadline to periodically check ctx
 			}
 
-			// fatal read error: stop connection
-			c.cancel()
+			// fatal read error: let manager reconnect or stop connection
+			c.log().Warn("read failed", "err", err)
+			select {
+			case c.lostCh <- err:
+			case <-c.ctx.Done():
+			}
 			return
 		}
 
 		// update last activity
-		atomic.StoreInt64(&c.lastActivity, time.Now().UnixNano())
+		now := time.Now().UnixNano()
+		atomic.StoreInt64(&c.lastActivity, now)
+		atomic.StoreInt64(&c.lastRecv, now)
+		c.countReceived(n)
+		c.trace(TraceReceived, buf[:n])
 
-		pkt, err := fromBytes(buf[:n])
+		pkt, err := DecodePacket(buf[:n])
 		if err != nil {
+			c.countBadPacket(err)
+			c.log().Debug("bad packet dropped", "size", n, "err", err)
 			continue // bad packet – ignore
 		}
 
 		select {
-		case c.pktCh <- pkt:
+		case c.pktCh <- &pkt:
 		case <-c.ctx.Done():
 			return
 		}
@@ -205,23 +425,33 @@ func (c *Connection) readerLoop() {
 }
 
 // loginOnce performs synchronous login handshake before loops start.
-func (c *Connection) loginOnce() error {
+// The handshake is bounded by the connection deadline and by ctx.
+func (c *Connection) loginOnce(ctx context.Context) error {
 	if c.conn == nil {
 		return ErrConnectionClosed
 	}
 
-	p := new(packet)
-	p.make([]byte(c.password), loginPacket, 0)
-	raw, err := p.toBytes()
-	if err != nil {
-		return err
-	}
+	// unblock a pending read as soon as ctx is done
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	raw := EncodePacket(Packet{Kind: LoginPacket, Data: []byte(c.password)})
 
 	buf := make([]byte, c.bufferSize)
 	step := max(c.timeouts.deadline/time.Duration(c.timeouts.loginAttempts), 1*time.Second)
 	globalDeadline := time.Now().Add(c.timeouts.deadline)
+	if d, ok := ctx.Deadline(); ok && d.Before(globalDeadline) {
+		globalDeadline = d
+	}
 
 	for range c.timeouts.loginAttempts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		now := time.Now()
 		if now.After(globalDeadline) {
 			return ErrLoginTimeout
@@ -230,6 +460,8 @@ func (c *Connection) loginOnce() error {
 		if _, err := c.conn.Write(raw); err != nil {
 			return err
 		}
+		c.countSent(len(raw))
+		c.trace(TraceSent, raw)
 
 		readDeadline := now.Add(step)
 		if readDeadline.After(globalDeadline) {
@@ -239,6 +471,10 @@ func (c *Connection) loginOnce() error {
 		_ = c.conn.SetReadDeadline(readDeadline)
 		n, err := c.conn.Read(buf)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
 			if ne, ok := err.(net.Error); ok && ne.Timeout() {
 				time.Sleep(c.timeouts.microSleep)
 				continue
@@ -247,12 +483,18 @@ func (c *Connection) loginOnce() error {
 			return err
 		}
 
-		resp, err := fromBytes(buf[:n])
-		if err != nil || resp.kind != loginPacket {
+		c.countReceived(n)
+		c.trace(TraceReceived, buf[:n])
+
+		resp, err := DecodePacket(buf[:n])
+		if err != nil || resp.Kind != LoginPacket {
+			if err != nil {
+				c.countBadPacket(err)
+			}
 			continue
 		}
 
-		if len(resp.data) == 0 || resp.data[0] != loginSuccess {
+		if len(resp.Data) == 0 || resp.Data[0] != loginSuccess {
 			return ErrLoginFailed
 		}
 
@@ -260,14 +502,18 @@ func (c *Connection) loginOnce() error {
 		return nil
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	return ErrLoginTimeout
 }
 
 // writePacket constructs and sends a packet of the specified type, data and sequence to the server.
 // NOTE: must be called only from managerLoop (single writer).
-func (c *Connection) writePacket(kind packetKind, data []byte, seq byte) error {
+func (c *Connection) writePacket(kind PacketKind, data []byte, seq byte) error {
 	// Protocol-level limit for command body (client never sends multipart)
-	if kind == commandPacket && len(data) > MaxCommandBodySize {
+	if kind == CommandPacket && len(data) > MaxCommandBodySize {
 		return ErrCommandTooLong
 	}
 
@@ -280,16 +526,12 @@ func (c *Connection) writePacket(kind packetKind, data []byte, seq byte) error {
 		return ErrConnectionClosed
 	}
 
-	pkt := new(packet)
-	pkt.make(data, kind, seq)
-	raw, err := pkt.toBytes()
-	if err != nil {
-		return err
-	}
-
-	_, err = c.conn.Write(raw)
+	raw := EncodePacket(Packet{Kind: kind, Seq: seq, Data: data})
+	_, err := c.conn.Write(raw)
 	if err == nil {
 		atomic.StoreInt64(&c.lastActivity, time.Now().UnixNano())
+		c.countSent(len(raw))
+		c.trace(TraceSent, raw)
 	}
 
 	return err
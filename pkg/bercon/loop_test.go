@@ -0,0 +1,174 @@
+package bercon
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newLoopConnection returns a Connection with only the state owned by
+// managerLoop initialized, enough to drive its handlers directly.
+func newLoopConnection() *Connection {
+	return &Connection{
+		inflight:   make(map[byte]*inflight),
+		quarantine: make(map[byte]time.Time),
+		seenMsgs:   new([256]seenMessage),
+		timeouts:   Timeouts{deadline: DefaultDeadlineTimeout * time.Second},
+	}
+}
+
+func multipartPacket(seq, pages, page byte, data string) *Packet {
+	return &Packet{Kind: CommandPacket, Seq: seq, Pages: pages, Page: page, Data: []byte(data)}
+}
+
+func TestHandleCommandPacket_Multipart(t *testing.T) {
+	cases := []struct {
+		name  string
+		order []byte
+		want  string
+	}{
+		{"in-order", []byte{0, 1, 2}, "abc"},
+		{"reversed", []byte{2, 1, 0}, "abc"},
+		{"shuffled", []byte{1, 2, 0}, "abc"},
+		{"duplicates", []byte{1, 1, 0, 1, 2, 0}, "abc"},
+	}
+
+	pages := []string{"a", "b", "c"}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newLoopConnection()
+			done := make(chan sendResp, 1)
+			c.inflight[7] = &inflight{done: done, ts: time.Now(), sends: 1}
+
+			for _, page := range tc.order {
+				c.handleCommandPacket(multipartPacket(7, 3, page, pages[page]))
+			}
+
+			select {
+			case resp := <-done:
+				if resp.err != nil {
+					t.Fatalf("unexpected error: %v", resp.err)
+				}
+				if string(resp.data) != tc.want {
+					t.Fatalf("got %q, want %q", resp.data, tc.want)
+				}
+			default:
+				t.Fatal("response not completed")
+			}
+
+			if _, ok := c.inflight[7]; ok {
+				t.Fatal("in-flight entry not released")
+			}
+		})
+	}
+}
+
+func TestHandleCommandPacket_MissingPage(t *testing.T) {
+	c := newLoopConnection()
+	done := make(chan sendResp, 1)
+	c.inflight[1] = &inflight{done: done, ts: time.Now(), sends: 1}
+
+	c.handleCommandPacket(multipartPacket(1, 3, 0, "a"))
+	c.handleCommandPacket(multipartPacket(1, 3, 2, "c"))
+
+	select {
+	case resp := <-done:
+		t.Fatalf("unexpected completion: %+v", resp)
+	default:
+	}
+
+	// the request only fails once the deadline passes
+	c.reapInflight(time.Now().Add(2 * c.timeouts.deadline))
+
+	resp := <-done
+	if !errors.Is(resp.err, ErrTimeout) {
+		t.Fatalf("got %v, want %v", resp.err, ErrTimeout)
+	}
+}
+
+func TestHandleCommandPacket_PageCountMismatch(t *testing.T) {
+	c := newLoopConnection()
+	done := make(chan sendResp, 1)
+	c.inflight[1] = &inflight{done: done, ts: time.Now(), sends: 1}
+
+	c.handleCommandPacket(multipartPacket(1, 3, 0, "a"))
+	c.handleCommandPacket(multipartPacket(1, 2, 1, "b"))
+
+	resp := <-done
+	if !errors.Is(resp.err, ErrBadPart) {
+		t.Fatalf("got %v, want %v", resp.err, ErrBadPart)
+	}
+}
+
+func TestDuplicateMessage(t *testing.T) {
+	c := newLoopConnection()
+	now := time.Now()
+
+	msg := &Packet{Kind: MessagePacket, Seq: 3, Data: []byte("(Global) Survivor: hi")}
+	if c.duplicateMessage(msg, now) {
+		t.Fatal("first copy reported as duplicate")
+	}
+	if !c.duplicateMessage(msg, now.Add(time.Second)) {
+		t.Fatal("resent copy not reported as duplicate")
+	}
+
+	// same sequence after the ring wrapped carries another payload
+	next := &Packet{Kind: MessagePacket, Seq: 3, Data: []byte("(Global) Survivor: bye")}
+	if c.duplicateMessage(next, now.Add(2*time.Second)) {
+		t.Fatal("new message with reused sequence reported as duplicate")
+	}
+
+	// identical payload long after the window is a new message
+	if c.duplicateMessage(next, now.Add(2*time.Second+2*messageDedupWindow)) {
+		t.Fatal("message outside of window reported as duplicate")
+	}
+}
+
+func TestReapInflight(t *testing.T) {
+	c := newLoopConnection()
+	c.timeouts.deadline = 50 * time.Millisecond
+	now := time.Now()
+
+	// seq 0 lost its response, seq 1 is still within the deadline
+	lost := make(chan sendResp, 1)
+	c.inflight[0] = &inflight{done: lost, ts: now.Add(-time.Second), sends: 1}
+	c.inflight[1] = &inflight{done: make(chan sendResp, 1), ts: now, sends: 1}
+	parked := make(chan sendResp, 1)
+	c.pending = []sendReq{{ts: now.Add(-time.Second), command: "players", respCh: parked}}
+
+	c.reapInflight(now)
+
+	if got := c.Reaped(); got != 2 {
+		t.Fatalf("got %d reaped, want 2", got)
+	}
+	for name, ch := range map[string]chan sendResp{"in-flight": lost, "parked": parked} {
+		select {
+		case resp := <-ch:
+			if !errors.Is(resp.err, ErrTimeout) {
+				t.Fatalf("%s request: got %v, want %v", name, resp.err, ErrTimeout)
+			}
+		default:
+			t.Fatalf("%s request not answered", name)
+		}
+	}
+	if _, ok := c.inflight[0]; ok {
+		t.Fatal("expired request still in flight")
+	}
+	if _, ok := c.inflight[1]; !ok {
+		t.Fatal("fresh request reaped")
+	}
+	if len(c.pending) != 0 {
+		t.Fatalf("got %d parked requests, want 0", len(c.pending))
+	}
+
+	// the reaped number is quarantined for one deadline, then reused
+	if seq, _ := c.tryFindFreeSeq(); seq != 2 {
+		t.Fatalf("got seq %d during quarantine, want 2", seq)
+	}
+	time.Sleep(2 * c.timeouts.deadline)
+	c.sequence = 0
+	if seq, ok := c.tryFindFreeSeq(); !ok || seq != 0 {
+		t.Fatalf("got seq %d, %v after quarantine, want 0", seq, ok)
+	}
+}
@@ -0,0 +1,90 @@
+package bercon
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Option configures a Connection before the transport is dialed and the
+// login handshake starts. Options follow the same normalization rules as
+// the matching Set* methods.
+type Option func(*Connection)
+
+// WithBufferSize sets the receive buffer size. See SetBufferSize.
+func WithBufferSize(size uint16) Option {
+	return func(c *Connection) { c.SetBufferSize(size) }
+}
+
+// WithDeadline sets the response deadline, also used for the login
+// handshake. See SetDeadline.
+func WithDeadline(d time.Duration) Option {
+	return func(c *Connection) { c.SetDeadline(d) }
+}
+
+// WithLoginAttempts sets the number of login attempts. See SetLoginAttempts.
+func WithLoginAttempts(attempts int) Option {
+	return func(c *Connection) { c.SetLoginAttempts(attempts) }
+}
+
+// WithMicroSleep sets the pause between login attempts. See SetMicroSleep.
+func WithMicroSleep(d time.Duration) Option {
+	return func(c *Connection) { c.SetMicroSleep(d) }
+}
+
+// WithKeepalive enables keepalive packets sent every d. See SetKeepalive
+// and StartKeepAlive.
+func WithKeepalive(d time.Duration) Option {
+	return func(c *Connection) {
+		c.SetKeepalive(d)
+		c.keepalive = true
+	}
+}
+
+// WithLocalAddr binds the UDP socket to the local address addr
+// ("ip:port", port may be 0). It has no effect with a custom dialer.
+func WithLocalAddr(addr string) Option {
+	return func(c *Connection) { c.localAddr = addr }
+}
+
+// WithDialer opens the transport with dial, also on reconnect.
+// A nil dial keeps the default connected UDP socket.
+func WithDialer(dial DialFunc) Option {
+	return func(c *Connection) { c.dial = dial }
+}
+
+// WithReconnect enables automatic reconnect. See SetReconnect.
+func WithReconnect(p ReconnectPolicy) Option {
+	return func(c *Connection) { c.SetReconnect(p) }
+}
+
+// WithRetransmit enables command retransmission. See SetRetransmit.
+func WithRetransmit(p RetransmitPolicy) Option {
+	return func(c *Connection) { c.SetRetransmit(p) }
+}
+
+// WithStateHook registers a state change hook that also observes the
+// initial connecting to logged-in transition. See OnStateChange.
+func WithStateHook(fn StateHook) Option {
+	return func(c *Connection) { c.OnStateChange(fn) }
+}
+
+// WithTrace records every raw packet sent and received to w as NDJSON
+// TraceRecord lines; see ReadTrace. Login passwords are redacted.
+// Writes are serialized but not buffered.
+func WithTrace(w io.Writer) Option {
+	return func(c *Connection) {
+		if w == nil {
+			c.tracer = nil
+			return
+		}
+		c.tracer = &tracer{enc: json.NewEncoder(w)}
+	}
+}
+
+// WithLogger sets the logger for connection diagnostics.
+// A nil logger disables logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Connection) { c.logger = l }
+}
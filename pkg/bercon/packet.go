@@ -5,211 +5,183 @@ import (
 	"hash/crc32"
 )
 
-type packetKind byte
+// PacketKind is the BattlEye RCON packet type.
+type PacketKind byte
 
 const (
-	loginPacket packetKind = iota
-	commandPacket
-	messagePacket
-	badPacket      packetKind = 0xFF
-	firstByte      byte       = 0x42
-	secondByte     byte       = 0x45
-	lastByte       byte       = 0xFF
-	loginSuccess   byte       = 0x01
-	multipart      byte       = 0x00
-	minPacketSize  int        = 8 // 7 header + 1 type + ... payload
-	packetOverhead            = 9
-)
+	// LoginPacket is a login request or login result.
+	LoginPacket PacketKind = 0x00
 
-/*
-BattleEye RCON packet header
-  - 0x42 0x45 | 0x00 0x00 0x00 0x00 | 0xFF -> BE CRC END
-*/
-type header struct {
-	be  [2]byte // BE - magic bytes
-	crc [4]byte // CRC sum of all data from 6 byte to end
-	end byte    // Header end
-}
+	// CommandPacket is a command request or command response.
+	CommandPacket PacketKind = 0x01
 
-/*
-BattleEye RCON packet
-  - header | 0x00 | ... -> login (type | data (status/pass))
-  - header | 0x01 | 0x42 ... -> cmd single (type | seq, data (msg))
-  - header | 0x01 | 0x43 0x00 0x06 0x00 ... -> cmd multi (type | seq, 0x00, pages, page#, data (msg))
-  - header | 0x02 | 0x44 ... -> message (type | seq, data (msg))
-*/
-type packet struct {
-	data   []byte     // data
-	header header     // use in all packets
-	kind   packetKind // define type of packet
-	seq    byte       // packet sequence number (for command/message)
-	pages  byte       // total pages (command multipart)
-	page   byte       // page index (command multipart)
-}
+	// MessagePacket is a server message or its acknowledgement.
+	MessagePacket PacketKind = 0x02
+)
 
-// make initializes fields; header/CRC are computed in toBytes().
-func (p *packet) make(data []byte, kind packetKind, seq byte) {
-	p.kind = kind
-	p.data = data
+const (
+	firstByte      byte = 0x42
+	secondByte     byte = 0x45
+	lastByte       byte = 0xFF
+	loginSuccess   byte = 0x01
+	multipart      byte = 0x00
+	headerSize     int  = 7 // 'B' 'E', CRC32 and the 0xFF terminator
+	minPacketSize  int  = 8 // header + type
+	packetOverhead      = 9
+	maxPages            = 255
+)
 
-	if kind != loginPacket {
-		p.seq = seq
+// String returns the packet type name.
+func (k PacketKind) String() string {
+	switch k {
+	case LoginPacket:
+		return "login"
+	case CommandPacket:
+		return "command"
+	case MessagePacket:
+		return "message"
+	default:
+		return "unknown"
 	}
 }
 
-// check CRC packet header
-func (p *packet) checkCRC(raw []byte) error {
-	got := binary.LittleEndian.Uint32(p.header.crc[:])
-	want := crc32.ChecksumIEEE(raw[6:])
-	if got != want {
-		return ErrPacketCRC
-	}
-
-	return nil
+/*
+Packet is a decoded BattlEye RCON datagram. On the wire every packet is
+a header (0x42 0x45 'BE', CRC32 of the rest from the 0xFF terminator on,
+0xFF) followed by:
+  - 0x00 | data -> login (password or result)
+  - 0x01 | seq | data -> command request or single-part response
+  - 0x01 | seq | 0x00 pages page | data -> multipart command response
+  - 0x02 | seq | data -> server message or its acknowledgement
+
+Seq is unused for login packets; Pages is non-zero only for multipart
+command responses.
+*/
+type Packet struct {
+	Data  []byte
+	Kind  PacketKind
+	Seq   byte
+	Pages byte
+	Page  byte
 }
 
-// toBytes builds the packet with a single allocation and computes CRC over out[6:].
-func (p *packet) toBytes() ([]byte, error) {
-	// Compute length: 7(header) + 1(kind) + seq? + multipart hdr? + len(data)
-	// header layout: [0]='B', [1]='E', [2..5]=CRC, [6]=0xFF
+// EncodePacket serializes p into a datagram with a single allocation and
+// computes its CRC.
+func EncodePacket(p Packet) []byte {
 	extra := 0
-	if p.kind != loginPacket {
+	if p.Kind != LoginPacket {
 		extra++ // seq
 	}
-
-	if p.kind == commandPacket && p.pages != 0 {
+	if p.Kind == CommandPacket && p.Pages != 0 {
 		extra += 3 // multipart header: 0x00, pages, page
 	}
 
-	total := 7 + 1 + extra + len(p.data)
-	out := make([]byte, total)
-
-	// magic header
-	out[0] = firstByte
-	out[1] = secondByte
-	out[6] = lastByte
+	out := make([]byte, minPacketSize+extra+len(p.Data))
+	out[0], out[1], out[6] = firstByte, secondByte, lastByte
 
-	// kind
-	i := 7
-	out[i] = byte(p.kind)
+	i := headerSize
+	out[i] = byte(p.Kind)
 	i++
 
-	// seq / multipart header
-	if p.kind != loginPacket {
-		out[i] = p.seq
+	if p.Kind != LoginPacket {
+		out[i] = p.Seq
 		i++
 	}
-
-	if p.kind == commandPacket && p.pages != 0 {
-		out[i+0] = multipart
-		out[i+1] = p.pages
-		out[i+2] = p.page
+	if p.Kind == CommandPacket && p.Pages != 0 {
+		out[i], out[i+1], out[i+2] = multipart, p.Pages, p.Page
 		i += 3
 	}
-
-	// payload
-	copy(out[i:], p.data)
+	copy(out[i:], p.Data)
 
 	// CRC over bytes from index 6 (0xFF) to the end
-	sum := crc32.ChecksumIEEE(out[6:])
-	binary.LittleEndian.PutUint32(out[2:6], sum)
+	binary.LittleEndian.PutUint32(out[2:6], crc32.ChecksumIEEE(out[6:]))
 
-	// quick header validation (cheap)
-	if err := checkPacket(out); err != nil {
-		return nil, err
-	}
-
-	return out, nil
+	return out
 }
 
-// checkPacket validates fixed header bytes and minimum size.
-func checkPacket(data []byte) error {
-	if len(data) < minPacketSize { // 7 header + 1 kind
-		return ErrPacketSize
-	}
-
-	if data[0] != firstByte || data[1] != secondByte || data[6] != lastByte {
-		return ErrPacketHeader
-	}
-
-	return nil
-}
-
-// fromBytes parses the raw bytes into packet struct with minimal allocations.
-// It copies only the payload into p.data; header and small fields are read by index.
-func fromBytes(raw []byte) (*packet, error) {
+// DecodePacket parses a datagram and verifies its header and CRC. Data of
+// the returned packet is a copy and does not retain raw; it is nil when
+// the packet has no payload.
+func DecodePacket(raw []byte) (Packet, error) {
 	if err := checkPacket(raw); err != nil {
-		return nil, err
+		return Packet{}, err
 	}
-
-	// parse header
-	p := new(packet)
-	copy(p.header.be[:], raw[0:2])
-	copy(p.header.crc[:], raw[2:6])
-	p.header.end = raw[6]
-
-	// verify CRC over raw[6:]
-	if err := p.checkCRC(raw); err != nil {
-		return nil, err
+	if binary.LittleEndian.Uint32(raw[2:6]) != crc32.ChecksumIEEE(raw[6:]) {
+		return Packet{}, ErrPacketCRC
 	}
 
-	// parse kind and body
-	i := 7
-	p.kind = packetKind(raw[i])
-	i++
+	p := Packet{Kind: PacketKind(raw[headerSize])}
+	i := headerSize + 1
 
-	switch p.kind {
-	case loginPacket:
+	switch p.Kind {
+	case LoginPacket:
 		// payload is the rest
 
-	case commandPacket:
-		// seq
+	case CommandPacket, MessagePacket:
 		if i >= len(raw) {
-			return nil, ErrPacketSize
+			return Packet{}, ErrPacketSize
 		}
-
-		p.seq = raw[i]
+		p.Seq = raw[i]
 		i++
 
-		// optional multipart header
-		// after kind(1)+seq(1), next byte may be 0x00 => multipart
-		if i < len(raw) && raw[i] == multipart {
+		// a command response may continue with a multipart header
+		if p.Kind == CommandPacket && i < len(raw) && raw[i] == multipart {
 			if i+3 > len(raw) {
-				return nil, ErrPacketSize
+				return Packet{}, ErrPacketSize
 			}
-
-			// consume delimiter + pages + page
-			i++
-			p.pages = raw[i]
-			i++
-			p.page = raw[i]
-			i++
+			p.Pages, p.Page = raw[i+1], raw[i+2]
+			i += 3
 		}
 
-	case messagePacket:
-		// seq
-		if i >= len(raw) {
-			return nil, ErrPacketSize
-		}
-		p.seq = raw[i]
-		i++
-
 	default:
-		return nil, ErrPacketUnknown
+		return Packet{}, ErrPacketUnknown
+	}
+
+	if n := len(raw) - i; n > 0 {
+		p.Data = make([]byte, n)
+		copy(p.Data, raw[i:])
+	}
+
+	return p, nil
+}
+
+// SplitResponse returns the command response packets a server sends for
+// resp to the command with sequence number seq: a single packet when resp
+// fits in pageSize bytes, otherwise evenly sized multipart pages of at
+// most pageSize bytes. pageSize must be positive. As a response has at
+// most 255 pages, pages of larger responses exceed pageSize.
+func SplitResponse(seq byte, resp []byte, pageSize int) []Packet {
+	if len(resp) <= pageSize {
+		return []Packet{{Kind: CommandPacket, Seq: seq, Data: resp}}
 	}
 
-	// copy payload (avoid retaining the large read buffer)
-	if i > len(raw) {
-		return nil, ErrPacketSize
+	pages := min((len(resp)+pageSize-1)/pageSize, maxPages)
+	size := (len(resp) + pages - 1) / pages
+	pages = (len(resp) + size - 1) / size // rounding up size may need fewer
+
+	out := make([]Packet, 0, pages)
+	for i := range pages {
+		out = append(out, Packet{
+			Kind:  CommandPacket,
+			Seq:   seq,
+			Pages: byte(pages), // #nosec G115 -- bounded to 255 above
+			Page:  byte(i),     // #nosec G115 -- bounded to 255 above
+			Data:  resp[i*size : min((i+1)*size, len(resp))],
+		})
 	}
 
-	n := len(raw) - i
-	if n > 0 {
-		p.data = make([]byte, n)
-		copy(p.data, raw[i:])
-	} else {
-		p.data = nil
+	return out
+}
+
+// checkPacket validates fixed header bytes and minimum size.
+func checkPacket(data []byte) error {
+	if len(data) < minPacketSize {
+		return ErrPacketSize
 	}
 
-	return p, nil
+	if data[0] != firstByte || data[1] != secondByte || data[6] != lastByte {
+		return ErrPacketHeader
+	}
+
+	return nil
 }
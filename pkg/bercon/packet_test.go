@@ -0,0 +1,83 @@
+package bercon
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodePacket(t *testing.T) {
+	cases := []Packet{
+		{Kind: LoginPacket, Data: []byte("secret")},
+		{Kind: CommandPacket, Seq: 7, Data: []byte("players")},
+		{Kind: CommandPacket, Seq: 7, Pages: 3, Page: 1, Data: []byte("page")},
+		{Kind: MessagePacket, Seq: 255, Data: []byte("(Global) Survivor: hi")},
+		{Kind: MessagePacket, Seq: 1},
+	}
+
+	for _, want := range cases {
+		got, err := DecodePacket(EncodePacket(want))
+		if err != nil {
+			t.Fatalf("%s: %v", want.Kind, err)
+		}
+		if got.Kind != want.Kind || got.Seq != want.Seq ||
+			got.Pages != want.Pages || got.Page != want.Page ||
+			!bytes.Equal(got.Data, want.Data) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestDecodePacketErrors(t *testing.T) {
+	raw := EncodePacket(Packet{Kind: CommandPacket, Seq: 1, Data: []byte("x")})
+	raw[len(raw)-1] ^= 0xFF
+
+	cases := []struct {
+		want error
+		raw  []byte
+	}{
+		{ErrPacketCRC, raw},
+		{ErrPacketSize, []byte("BE")},
+		{ErrPacketHeader, []byte("XX\x00\x00\x00\x00\xff\x01")},
+		{ErrPacketUnknown, EncodePacket(Packet{Kind: 0x07})},
+		{ErrPacketSize, EncodePacket(Packet{Kind: CommandPacket, Seq: 1, Data: []byte{multipart}})},
+	}
+
+	for _, tc := range cases {
+		if _, err := DecodePacket(tc.raw); !errors.Is(err, tc.want) {
+			t.Fatalf("%x: got %v, want %v", tc.raw, err, tc.want)
+		}
+	}
+}
+
+func TestSplitResponse(t *testing.T) {
+	resp := []byte(strings.Repeat("x", 2500))
+	pages := SplitResponse(9, resp, 1000)
+	if len(pages) != 3 {
+		t.Fatalf("got %d pages, want 3", len(pages))
+	}
+
+	var got []byte
+	for i, p := range pages {
+		if p.Kind != CommandPacket || p.Seq != 9 || p.Pages != 3 || int(p.Page) != i || len(p.Data) > 1000 {
+			t.Fatalf("page %d: %+v", i, p)
+		}
+		got = append(got, p.Data...)
+	}
+	if !bytes.Equal(got, resp) {
+		t.Fatal("pages do not reassemble to the response")
+	}
+
+	if pages := SplitResponse(1, []byte("ok"), 1000); len(pages) != 1 || pages[0].Pages != 0 {
+		t.Fatalf("short response split into %+v", pages)
+	}
+	pages = SplitResponse(1, make([]byte, 1000), 1)
+	n := 0
+	for _, p := range pages {
+		n += len(p.Data)
+	}
+	if len(pages) > 255 || int(pages[0].Pages) != len(pages) || n != 1000 {
+		t.Fatalf("1000 bytes split into %d pages of %d bytes", len(pages), n)
+	}
+}
@@ -0,0 +1,223 @@
+package bercon
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Default reconnect policy values.
+const (
+	// DefaultReconnectBackoff is the initial delay before a reconnect attempt.
+	DefaultReconnectBackoff = 1 * time.Second
+
+	// DefaultReconnectMaxBackoff caps the exponential reconnect backoff.
+	DefaultReconnectMaxBackoff = 30 * time.Second
+)
+
+// ReconnectPolicy describes how a Connection recovers a lost session.
+//
+// When the session drops (fatal socket error, or no packets from the server
+// for longer than keepalive+deadline while keepalive is enabled), the
+// connection re-dials the server and repeats the login handshake. Pending
+// Send() calls fail with ErrConnectionDown, while the Messages channel and
+// subscriptions stay open across reconnects and are closed only when the
+// connection gives up. Delays grow exponentially from Backoff up to
+// MaxBackoff. The connection gives up with ErrReconnectFailed after
+// MaxAttempts failed attempts, or with ErrReconnectWindow once MaxWindow has
+// elapsed since the session was lost; zero values mean unlimited.
+type ReconnectPolicy struct {
+	Backoff     time.Duration // initial delay before each attempt
+	MaxBackoff  time.Duration // upper bound for delay growth
+	MaxWindow   time.Duration // total time allowed to reconnect, 0 = unlimited
+	MaxAttempts int           // attempts before giving up, 0 = unlimited
+}
+
+// SetReconnect enables automatic reconnect using the given policy.
+// Zero Backoff/MaxBackoff use DefaultReconnectBackoff/DefaultReconnectMaxBackoff.
+// Call it right after Open, before the connection is used concurrently.
+// It has no effect on connections opened with OpenWithConn or
+// OpenWithPacketConn without WithDialer, which cannot re-establish their
+// transport. Prefer WithReconnect with OpenWithOptions.
+func (c *Connection) SetReconnect(p ReconnectPolicy) {
+	if p.Backoff <= 0 {
+		p.Backoff = DefaultReconnectBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultReconnectMaxBackoff
+	}
+	if p.MaxBackoff < p.Backoff {
+		p.MaxBackoff = p.Backoff
+	}
+	if p.MaxAttempts < 0 {
+		p.MaxAttempts = 0
+	}
+
+	c.reconnect = &p
+}
+
+// DisableReconnect turns automatic reconnect off (the default): a lost
+// session permanently stops the connection.
+func (c *Connection) DisableReconnect() {
+	c.reconnect = nil
+}
+
+// silent reports whether nothing was received from the server for longer
+// than a keepalive round trip, which means the session is gone.
+// NOTE: must be called only from managerLoop.
+func (c *Connection) silent() bool {
+	last := atomic.LoadInt64(&c.lastRecv)
+	return time.Since(time.Unix(0, last)) > c.timeouts.keepalive+c.timeouts.deadline
+}
+
+// recoverSession handles a lost session. It returns false when the connection has
+// been stopped and managerLoop must exit.
+// NOTE: must be called only from managerLoop.
+func (c *Connection) recoverSession(cause error) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+
+	c.setState(StateLost, cause)
+	c.log().Warn("session lost", "addr", c.address, "err", cause)
+
+	if c.reconnect == nil || c.dial == nil {
+		c.fail(cause)
+		return false
+	}
+
+	if err := c.reconnectSession(); err != nil {
+		if c.ctx.Err() == nil {
+			c.fail(err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// reconnectSession drops the current socket and re-dials with backoff until
+// login succeeds or the policy limits are exceeded.
+func (c *Connection) reconnectSession() error {
+	atomic.StoreUint32(&c.alive, 0)
+	c.dropSession(ErrConnectionDown)
+	c.setState(StateReconnecting, nil)
+
+	p := c.reconnect
+	start := time.Now()
+	backoff := p.Backoff
+
+	for attempt := 1; ; attempt++ {
+		if p.MaxAttempts > 0 && attempt > p.MaxAttempts {
+			return ErrReconnectFailed
+		}
+
+		if p.MaxWindow > 0 && time.Since(start)+backoff > p.MaxWindow {
+			return ErrReconnectWindow
+		}
+
+		wait := time.NewTimer(backoff)
+		select {
+		case <-c.ctx.Done():
+			wait.Stop()
+			return ErrConnectionClosed
+
+		case <-wait.C:
+		}
+
+		err := c.redial()
+		if err == nil {
+			c.log().Info("reconnected", "addr", c.address, "attempt", attempt)
+			return nil
+		}
+		c.log().Debug("reconnect attempt failed", "addr", c.address, "attempt", attempt, "err", err)
+
+		backoff = min(backoff*2, p.MaxBackoff)
+	}
+}
+
+// dropSession closes the current socket, fails all in-flight and parked requests with
+// err and discards packets that still belong to the old session.
+func (c *Connection) dropSession(err error) {
+	c.connMu.Lock()
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+	c.connMu.Unlock()
+
+	for seq, holder := range c.inflight {
+		delete(c.inflight, seq)
+		holder.done <- sendResp{data: nil, err: err}
+	}
+
+	for _, req := range c.pending {
+		req.respCh <- sendResp{data: nil, err: err}
+	}
+	c.pending = nil
+	clear(c.quarantine)
+	*c.seenMsgs = [256]seenMessage{}
+
+	for {
+		select {
+		case <-c.pktCh:
+		default:
+			return
+		}
+	}
+}
+
+// redial opens a new socket, logs in and starts a reader for it.
+func (c *Connection) redial() error {
+	rawConn, err := c.dial(c.ctx, c.address)
+	if err != nil {
+		return err
+	}
+
+	c.connMu.Lock()
+	if c.ctx.Err() != nil {
+		c.connMu.Unlock()
+		_ = rawConn.Close()
+		return ErrConnectionClosed
+	}
+	c.conn = rawConn
+	c.connMu.Unlock()
+
+	if err := c.loginOnce(c.ctx); err != nil {
+		_ = rawConn.Close()
+		return err
+	}
+
+	now := time.Now().UnixNano()
+	atomic.StoreInt64(&c.lastActivity, now)
+	atomic.StoreInt64(&c.lastRecv, now)
+	atomic.StoreUint32(&c.alive, 1)
+	atomic.AddUint64(&c.stats.reconnects, 1)
+	c.lastCommand = time.Now()
+	c.setState(StateLoggedIn, nil)
+
+	c.wg.Add(1)
+	go c.readerLoop(rawConn)
+
+	return nil
+}
+
+// fail permanently stops the connection with err. Messages and all
+// subscriptions are closed once dispatchLoop has delivered its last event.
+func (c *Connection) fail(err error) {
+	c.errMu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.errMu.Unlock()
+
+	atomic.StoreUint32(&c.alive, 0)
+	c.cancel()
+	c.setState(StateClosed, err)
+	c.log().Error("connection stopped", "addr", c.address, "err", err)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		<-c.dispatched
+		c.closeSubscriptions()
+	}()
+}
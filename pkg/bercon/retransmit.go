@@ -0,0 +1,80 @@
+package bercon
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRetransmitInterval is the default time to wait for a response
+// before a command is written again.
+const DefaultRetransmitInterval = 1 * time.Second
+
+// RetransmitPolicy describes how commands are repeated on UDP packet loss.
+//
+// When no (complete) response arrives within Interval, the command is sent
+// again with the same sequence number, up to Attempts transmissions in total.
+// Duplicate responses and multipart pages caused by a retransmission are
+// ignored, and the sequence number is not reused until late copies can no
+// longer arrive. The connection deadline still bounds the whole exchange,
+// so it should be at least Interval*Attempts.
+type RetransmitPolicy struct {
+	Interval time.Duration // wait before resending the same command
+	Attempts int           // total transmissions, including the first one
+}
+
+// SetRetransmit enables command retransmission using the given policy.
+// Zero Interval uses DefaultRetransmitInterval; Attempts < 2 disables it.
+func (c *Connection) SetRetransmit(p RetransmitPolicy) {
+	if p.Attempts < 2 {
+		c.retransmit = nil
+		return
+	}
+
+	if p.Interval <= 0 {
+		p.Interval = DefaultRetransmitInterval
+	}
+
+	c.retransmit = &p
+}
+
+// Retransmits returns how many times commands were written again because
+// their response did not arrive in time.
+func (c *Connection) Retransmits() uint64 {
+	return atomic.LoadUint64(&c.stats.retransmits)
+}
+
+// housekeepingInterval returns how often managerLoop checks in-flight
+// requests for retransmission and expiry.
+func (c *Connection) housekeepingInterval() time.Duration {
+	d := c.timeouts.deadline / 2
+	if p := c.retransmit; p != nil {
+		d = min(d, p.Interval/2)
+	}
+
+	return max(d, minReapInterval)
+}
+
+// retransmitInflight writes again commands whose response is overdue.
+// NOTE: must be called only from managerLoop.
+func (c *Connection) retransmitInflight(now time.Time) {
+	p := c.retransmit
+	if p == nil {
+		return
+	}
+
+	for seq, holder := range c.inflight {
+		if holder.sends >= p.Attempts || now.Sub(holder.sent) < p.Interval {
+			continue
+		}
+
+		if err := c.writePacket(CommandPacket, holder.cmd, seq); err != nil {
+			c.log().Warn("command retransmit failed", "seq", seq, "err", err)
+			continue
+		}
+
+		holder.sends++
+		holder.sent = now
+		atomic.AddUint64(&c.stats.retransmits, 1)
+		c.log().Debug("command retransmitted", "seq", seq, "sends", holder.sends)
+	}
+}
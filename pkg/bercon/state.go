@@ -0,0 +1,139 @@
+package bercon
+
+import "time"
+
+// State is the lifecycle state of a Connection.
+type State int
+
+const (
+	// StateConnecting means the socket is being dialed and logged in.
+	StateConnecting State = iota
+
+	// StateLoggedIn means the session is established and in use.
+	StateLoggedIn
+
+	// StateIdle means the session is established, but no command was sent
+	// for a keepalive interval and none is in flight.
+	StateIdle
+
+	// StateLost means the session dropped: a fatal socket error, or server
+	// silence detected while reconnect is enabled.
+	StateLost
+
+	// StateReconnecting means a lost session is being re-established.
+	StateReconnecting
+
+	// StateClosed is terminal: Close was called or the connection gave up.
+	StateClosed
+)
+
+// String returns the state name.
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateLoggedIn:
+		return "logged-in"
+	case StateIdle:
+		return "idle"
+	case StateLost:
+		return "lost"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// StateHook is called on every state transition. err carries the cause for
+// StateLost and for StateClosed when the connection stopped on its own.
+type StateHook func(from, to State, err error)
+
+type stateChange struct {
+	err      error
+	from, to State
+}
+
+// State returns the current lifecycle state.
+func (c *Connection) State() State {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	return c.state
+}
+
+// OnStateChange registers fn to be called on state transitions. Hooks are
+// called in registration order from a dedicated goroutine, one transition
+// at a time, so they may call Send or Close but should return promptly.
+// Transitions made before registration may or may not be reported.
+func (c *Connection) OnStateChange(fn StateHook) {
+	if fn == nil {
+		return
+	}
+
+	c.stateMu.Lock()
+	c.stateHooks = append(c.stateHooks, fn)
+	c.stateMu.Unlock()
+}
+
+// setState records a transition and queues it for the hooks. Transitions
+// out of StateClosed are ignored.
+func (c *Connection) setState(s State, err error) {
+	c.stateMu.Lock()
+	from := c.state
+	if from == s || from == StateClosed {
+		c.stateMu.Unlock()
+		return
+	}
+
+	c.state = s
+	c.stateQueue = append(c.stateQueue, stateChange{from: from, to: s, err: err})
+	c.stateMu.Unlock()
+
+	select {
+	case c.stateSignal <- struct{}{}:
+	default:
+	}
+}
+
+// stateLoop delivers queued transitions to hooks until StateClosed.
+func (c *Connection) stateLoop() {
+	for range c.stateSignal {
+		c.stateMu.Lock()
+		queue := c.stateQueue
+		c.stateQueue = nil
+		hooks := c.stateHooks
+		c.stateMu.Unlock()
+
+		for _, ch := range queue {
+			for _, fn := range hooks {
+				fn(ch.from, ch.to, ch.err)
+			}
+
+			if ch.to == StateClosed {
+				return
+			}
+		}
+	}
+}
+
+// updateIdle moves the session between StateLoggedIn and StateIdle.
+// NOTE: must be called only from managerLoop.
+func (c *Connection) updateIdle(now time.Time) {
+	if !c.IsAlive() {
+		return
+	}
+
+	idle := len(c.inflight) == 0 && len(c.pending) == 0 &&
+		now.Sub(c.lastCommand) >= c.timeouts.keepalive
+
+	switch {
+	case idle && c.State() == StateLoggedIn:
+		c.setState(StateIdle, nil)
+
+	case !idle && c.State() == StateIdle:
+		c.setState(StateLoggedIn, nil)
+	}
+}
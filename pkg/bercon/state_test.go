@@ -0,0 +1,45 @@
+package bercon
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStateHooks(t *testing.T) {
+	c := &Connection{stateSignal: make(chan struct{}, 1)}
+
+	var got []stateChange
+	c.OnStateChange(func(from, to State, err error) {
+		got = append(got, stateChange{from: from, to: to, err: err})
+	})
+
+	lost := errors.New("lost")
+	c.setState(StateLoggedIn, nil)
+	c.setState(StateLoggedIn, nil) // same state, ignored
+	c.setState(StateLost, lost)
+	c.setState(StateReconnecting, nil)
+	c.setState(StateClosed, nil)
+	c.setState(StateLoggedIn, nil) // closed is terminal
+
+	// returns once StateClosed was delivered
+	c.stateLoop()
+
+	want := []stateChange{
+		{from: StateConnecting, to: StateLoggedIn},
+		{from: StateLoggedIn, to: StateLost, err: lost},
+		{from: StateLost, to: StateReconnecting},
+		{from: StateReconnecting, to: StateClosed},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d transitions, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("transition %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if c.State() != StateClosed {
+		t.Fatalf("got state %s, want %s", c.State(), StateClosed)
+	}
+}
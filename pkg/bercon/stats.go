@@ -0,0 +1,188 @@
+package bercon
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rttWindow is the number of recent command round trips kept for
+// average and percentile calculation.
+const rttWindow = 256
+
+// Stats is a point-in-time snapshot of connection counters and health.
+// Counters are cumulative since Open and survive reconnects.
+type Stats struct {
+	LastReceived time.Time // last packet received from the server
+
+	PacketsSent     uint64 // packets written, including login, acks and keepalives
+	BytesSent       uint64
+	PacketsReceived uint64 // datagrams read, valid or not
+	BytesReceived   uint64
+
+	CommandsOK       uint64 // Send calls answered by the server
+	CommandsFailed   uint64 // Send calls failed for any reason but a timeout
+	CommandsTimedOut uint64 // Send calls that hit the deadline or ctx deadline
+
+	CRCErrors      uint64 // packets dropped by readerLoop on CRC mismatch
+	UnknownPackets uint64 // packets dropped by readerLoop with unknown type
+	BadPackets     uint64 // packets dropped by readerLoop with bad size/header
+
+	MessagesDropped   uint64 // server events lost because dispatch was congested
+	SubscriberDrops   uint64 // events discarded by full subscriptions (incl. Messages)
+	DuplicateMessages uint64 // resent message packets suppressed
+	Retransmits       uint64 // commands written again after packet loss
+	Reaped            uint64 // in-flight requests dropped without a response
+	Reconnects        uint64 // successful automatic reconnects
+
+	InFlight int // commands waiting for a response
+	Pending  int // commands waiting for a free sequence number
+
+	LastRTT time.Duration // round trip of the last answered command
+	AvgRTT  time.Duration // mean round trip over the recent window
+	P95RTT  time.Duration // 95th percentile round trip over the recent window
+
+	Alive bool
+}
+
+// counters holds the atomic counters behind Stats.
+type counters struct {
+	packetsSent, bytesSent         uint64
+	packetsReceived, bytesReceived uint64
+
+	commandsOK, commandsFailed, commandsTimedOut uint64
+
+	crcErrors, unknownPackets, badPackets uint64
+
+	messagesDropped, subscriberDrops, duplicates uint64
+	retransmits, reaped, reconnects              uint64
+
+	inflight, pending int64
+}
+
+// rttRing keeps the most recent command round trips.
+type rttRing struct {
+	samples [rttWindow]time.Duration
+	mu      sync.Mutex
+	next    int
+	count   int
+}
+
+func (r *rttRing) add(d time.Duration) {
+	r.mu.Lock()
+	r.samples[r.next] = d
+	r.next = (r.next + 1) % rttWindow
+	r.count = min(r.count+1, rttWindow)
+	r.mu.Unlock()
+}
+
+// summary returns the last, mean and 95th percentile round trip.
+func (r *rttRing) summary() (last, avg, p95 time.Duration) {
+	r.mu.Lock()
+	if r.count == 0 {
+		r.mu.Unlock()
+		return 0, 0, 0
+	}
+
+	last = r.samples[(r.next+rttWindow-1)%rttWindow]
+	window := slices.Clone(r.samples[:r.count])
+	r.mu.Unlock()
+
+	var sum time.Duration
+	for _, d := range window {
+		sum += d
+	}
+	avg = sum / time.Duration(len(window))
+
+	slices.Sort(window)
+	p95 = window[(len(window)*95+99)/100-1]
+
+	return last, avg, p95
+}
+
+// Stats returns a snapshot of the connection counters.
+func (c *Connection) Stats() Stats {
+	s := &c.stats
+	st := Stats{
+		LastReceived: time.Unix(0, atomic.LoadInt64(&c.lastRecv)),
+
+		PacketsSent:     atomic.LoadUint64(&s.packetsSent),
+		BytesSent:       atomic.LoadUint64(&s.bytesSent),
+		PacketsReceived: atomic.LoadUint64(&s.packetsReceived),
+		BytesReceived:   atomic.LoadUint64(&s.bytesReceived),
+
+		CommandsOK:       atomic.LoadUint64(&s.commandsOK),
+		CommandsFailed:   atomic.LoadUint64(&s.commandsFailed),
+		CommandsTimedOut: atomic.LoadUint64(&s.commandsTimedOut),
+
+		CRCErrors:      atomic.LoadUint64(&s.crcErrors),
+		UnknownPackets: atomic.LoadUint64(&s.unknownPackets),
+		BadPackets:     atomic.LoadUint64(&s.badPackets),
+
+		MessagesDropped:   atomic.LoadUint64(&s.messagesDropped),
+		SubscriberDrops:   atomic.LoadUint64(&s.subscriberDrops),
+		DuplicateMessages: atomic.LoadUint64(&s.duplicates),
+		Retransmits:       atomic.LoadUint64(&s.retransmits),
+		Reaped:            atomic.LoadUint64(&s.reaped),
+		Reconnects:        atomic.LoadUint64(&s.reconnects),
+
+		InFlight: int(atomic.LoadInt64(&s.inflight)),
+		Pending:  int(atomic.LoadInt64(&s.pending)),
+
+		Alive: c.IsAlive(),
+	}
+
+	st.LastRTT, st.AvgRTT, st.P95RTT = c.rtt.summary()
+
+	return st
+}
+
+// countSent records a packet written to the server.
+func (c *Connection) countSent(n int) {
+	atomic.AddUint64(&c.stats.packetsSent, 1)
+	atomic.AddUint64(&c.stats.bytesSent, uint64(n)) // #nosec G115
+}
+
+// countReceived records a datagram read from the server.
+func (c *Connection) countReceived(n int) {
+	atomic.AddUint64(&c.stats.packetsReceived, 1)
+	atomic.AddUint64(&c.stats.bytesReceived, uint64(n)) // #nosec G115
+}
+
+// countBadPacket records a datagram dropped by the parser.
+func (c *Connection) countBadPacket(err error) {
+	switch {
+	case errors.Is(err, ErrPacketCRC):
+		atomic.AddUint64(&c.stats.crcErrors, 1)
+
+	case errors.Is(err, ErrPacketUnknown):
+		atomic.AddUint64(&c.stats.unknownPackets, 1)
+
+	default:
+		atomic.AddUint64(&c.stats.badPackets, 1)
+	}
+}
+
+// countCommand records the outcome of a Send call.
+func (c *Connection) countCommand(err error) {
+	switch {
+	case err == nil:
+		atomic.AddUint64(&c.stats.commandsOK, 1)
+
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		atomic.AddUint64(&c.stats.commandsTimedOut, 1)
+
+	default:
+		atomic.AddUint64(&c.stats.commandsFailed, 1)
+	}
+}
+
+// syncQueueStats publishes manager-owned queue sizes.
+// NOTE: must be called only from managerLoop.
+func (c *Connection) syncQueueStats() {
+	atomic.StoreInt64(&c.stats.inflight, int64(len(c.inflight)))
+	atomic.StoreInt64(&c.stats.pending, int64(len(c.pending)))
+}
@@ -0,0 +1,38 @@
+package bercon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRTTRing_Summary(t *testing.T) {
+	var r rttRing
+
+	if last, avg, p95 := r.summary(); last != 0 || avg != 0 || p95 != 0 {
+		t.Fatalf("empty ring: got %v/%v/%v, want zeros", last, avg, p95)
+	}
+
+	// 1..100ms, then overwrite the window with 300 more samples
+	for i := 1; i <= 100; i++ {
+		r.add(time.Duration(i) * time.Millisecond)
+	}
+
+	last, avg, p95 := r.summary()
+	if last != 100*time.Millisecond {
+		t.Errorf("last = %v, want 100ms", last)
+	}
+	if avg != 50500*time.Microsecond {
+		t.Errorf("avg = %v, want 50.5ms", avg)
+	}
+	if p95 != 95*time.Millisecond {
+		t.Errorf("p95 = %v, want 95ms", p95)
+	}
+
+	for range 300 {
+		r.add(time.Second)
+	}
+
+	if _, avg, p95 := r.summary(); avg != time.Second || p95 != time.Second {
+		t.Errorf("after wrap avg/p95 = %v/%v, want 1s/1s", avg, p95)
+	}
+}
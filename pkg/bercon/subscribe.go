@@ -0,0 +1,208 @@
+package bercon
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultMessagesBufferSize is the buffer of the default Messages subscriber.
+const DefaultMessagesBufferSize = 32
+
+// DropPolicy selects what a subscription does with a new event while its
+// buffer is full.
+type DropPolicy int
+
+const (
+	// DropNewest discards the incoming event (default).
+	DropNewest DropPolicy = iota
+
+	// DropOldest discards the oldest buffered event to make room.
+	DropOldest
+
+	// Block waits until the consumer reads. A stalled Block subscriber
+	// delays delivery to every other subscriber of the connection.
+	Block
+)
+
+// Subscription is an independent consumer of server events (login and
+// message packets). Events are delivered on C until it is closed by
+// Unsubscribe, Connection.Close or the connection stopping on its own.
+// PacketEvent.Data is shared between subscribers and must be treated as
+// read-only.
+type Subscription struct {
+	C <-chan PacketEvent
+
+	conn    *Connection
+	ch      chan PacketEvent
+	filter  func(PacketEvent) bool
+	done    chan struct{}
+	dropped uint64 // atomic
+	mu      sync.Mutex
+	once    sync.Once
+	policy  DropPolicy
+	closed  bool
+}
+
+// Subscribe registers a new consumer of server events. filter selects the
+// events to deliver (nil accepts all), bufferSize sets the channel buffer and
+// policy decides what happens when the buffer is full. Non-blocking policies
+// always use a buffer of at least one event. C is closed when the
+// connection is closed or stops for good (reconnect gave up or the session
+// was lost without reconnect), after which Connection.Err reports why.
+// Subscribing to a closed connection returns an already closed
+// subscription.
+func (c *Connection) Subscribe(filter func(PacketEvent) bool, bufferSize int, policy DropPolicy) *Subscription {
+	if bufferSize < 0 || (bufferSize == 0 && policy != Block) {
+		bufferSize = 1
+	}
+
+	ch := make(chan PacketEvent, bufferSize)
+	return c.subscribe(ch, filter, policy)
+}
+
+func (c *Connection) subscribe(ch chan PacketEvent, filter func(PacketEvent) bool, policy DropPolicy) *Subscription {
+	s := &Subscription{
+		C:      ch,
+		conn:   c,
+		ch:     ch,
+		filter: filter,
+		done:   make(chan struct{}),
+		policy: policy,
+	}
+
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+
+	if c.subsClosed {
+		s.close()
+		return s
+	}
+
+	// copy-on-write, dispatchLoop iterates over a snapshot
+	subs := make([]*Subscription, 0, len(c.subs)+1)
+	subs = append(subs, c.subs...)
+	c.subs = append(subs, s)
+
+	return s
+}
+
+// IsMessage is a Subscribe filter for server message lines. It skips the
+// login results delivered after a reconnect, which carry a single status
+// byte.
+func IsMessage(ev PacketEvent) bool {
+	return len(ev.Data) > 1
+}
+
+// Dropped returns how many events were discarded for this subscription
+// because its buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Unsubscribe stops delivery and closes C. It is safe to call more than once.
+func (s *Subscription) Unsubscribe() {
+	c := s.conn
+	c.subsMu.Lock()
+	for i, sub := range c.subs {
+		if sub == s {
+			subs := make([]*Subscription, 0, len(c.subs)-1)
+			subs = append(subs, c.subs[:i]...)
+			c.subs = append(subs, c.subs[i+1:]...)
+			break
+		}
+	}
+	c.subsMu.Unlock()
+
+	s.close()
+}
+
+// close closes the channel once; done is closed first so that a Block
+// delivery in progress gives up and releases the lock.
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.done)
+
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+// deliver passes ev to the subscriber according to its filter and policy.
+func (s *Subscription) deliver(ctx context.Context, ev PacketEvent) {
+	if s.filter != nil && !s.filter(ev) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- ev:
+		case <-s.done:
+		case <-ctx.Done():
+		}
+
+	case DropOldest:
+		// dispatchLoop is the only sender, so this terminates quickly
+		for {
+			select {
+			case s.ch <- ev:
+				return
+			default:
+			}
+
+			select {
+			case old := <-s.ch:
+				s.countDrop(old)
+			default:
+			}
+		}
+
+	default:
+		select {
+		case s.ch <- ev:
+		default:
+			s.countDrop(ev)
+		}
+	}
+}
+
+func (s *Subscription) countDrop(ev PacketEvent) {
+	atomic.AddUint64(&s.dropped, 1)
+	atomic.AddUint64(&s.conn.stats.subscriberDrops, 1)
+	s.conn.log().Debug("subscriber event dropped", "seq", ev.Seq)
+}
+
+// publish fans ev out to all current subscribers.
+func (c *Connection) publish(ev PacketEvent) {
+	c.subsMu.RLock()
+	subs := c.subs
+	c.subsMu.RUnlock()
+
+	for _, s := range subs {
+		s.deliver(c.ctx, ev)
+	}
+}
+
+// closeSubscriptions closes every subscription, including Messages, and
+// rejects new ones.
+func (c *Connection) closeSubscriptions() {
+	c.subsMu.Lock()
+	subs := c.subs
+	c.subs = nil
+	c.subsClosed = true
+	c.subsMu.Unlock()
+
+	for _, s := range subs {
+		s.close()
+	}
+}
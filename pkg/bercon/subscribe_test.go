@@ -0,0 +1,99 @@
+package bercon
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscription_DropPolicies(t *testing.T) {
+	cases := []struct {
+		name    string
+		want    []byte // sequences left in buffer
+		policy  DropPolicy
+		dropped uint64
+	}{
+		{name: "drop-newest", policy: DropNewest, want: []byte{0, 1}, dropped: 2},
+		{name: "drop-oldest", policy: DropOldest, want: []byte{2, 3}, dropped: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Connection{ctx: context.Background()}
+			s := c.Subscribe(nil, 2, tc.policy)
+
+			for seq := range byte(4) {
+				s.deliver(context.Background(), PacketEvent{Seq: seq})
+			}
+
+			if got := s.Dropped(); got != tc.dropped {
+				t.Fatalf("dropped %d, want %d", got, tc.dropped)
+			}
+
+			s.Unsubscribe()
+
+			var got []byte
+			for ev := range s.C {
+				got = append(got, ev.Seq)
+			}
+
+			if string(got) != string(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubscription_FilterAndFanOut(t *testing.T) {
+	c := &Connection{ctx: context.Background()}
+	all := c.Subscribe(nil, 4, DropNewest)
+	odd := c.Subscribe(func(ev PacketEvent) bool { return ev.Seq%2 == 1 }, 4, DropNewest)
+
+	for seq := range byte(4) {
+		c.publish(PacketEvent{Seq: seq})
+	}
+
+	c.closeSubscriptions()
+
+	if n := len(collect(all)); n != 4 {
+		t.Fatalf("all subscriber got %d events, want 4", n)
+	}
+	if got := collect(odd); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("filtered subscriber got %v, want [1 3]", got)
+	}
+
+	// subscribing after close yields a closed subscription
+	late := c.Subscribe(nil, 1, DropNewest)
+	if _, ok := <-late.C; ok {
+		t.Fatal("subscription after close is open")
+	}
+}
+
+func TestSubscription_UnsubscribeUnblocks(t *testing.T) {
+	c := &Connection{ctx: context.Background()}
+	s := c.Subscribe(nil, 0, Block)
+
+	delivered := make(chan struct{})
+	go func() {
+		c.publish(PacketEvent{Seq: 1})
+		close(delivered)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	s.Unsubscribe()
+
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("blocked delivery not released by Unsubscribe")
+	}
+}
+
+func collect(s *Subscription) []byte {
+	var seqs []byte
+	for ev := range s.C {
+		seqs = append(seqs, ev.Seq)
+	}
+
+	return seqs
+}
@@ -0,0 +1,104 @@
+package bercon
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Trace record directions, relative to the client.
+const (
+	TraceSent     = "tx" // packet written by the client
+	TraceReceived = "rx" // packet received from the server
+)
+
+// TraceRecord is one raw packet of a trace, stored as a line of NDJSON.
+// Hex holds the whole datagram including header and CRC, so it can be
+// decoded with `xxd -r -p` or replayed by bercontest. Kind and Seq are
+// informational and absent for packets with a broken header.
+type TraceRecord struct {
+	Time     time.Time `json:"time"`
+	Seq      *byte     `json:"seq,omitempty"`
+	Dir      string    `json:"dir"`
+	Kind     string    `json:"kind,omitempty"`
+	Hex      string    `json:"hex"`
+	Len      int       `json:"len"`
+	Redacted bool      `json:"redacted,omitempty"`
+}
+
+// Raw decodes the datagram stored in Hex.
+func (r TraceRecord) Raw() ([]byte, error) {
+	return hex.DecodeString(r.Hex)
+}
+
+// ReadTrace reads NDJSON trace records written with WithTrace.
+func ReadTrace(r io.Reader) ([]TraceRecord, error) {
+	var records []TraceRecord
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+
+		var rec TraceRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	return records, sc.Err()
+}
+
+// tracer serializes trace records to a writer.
+type tracer struct {
+	enc    *json.Encoder
+	failed bool
+	mu     sync.Mutex
+}
+
+// trace records raw as a packet sent or received on the current session.
+// The password in login requests is replaced by an empty one before the
+// packet is stored. Safe for concurrent use.
+func (c *Connection) trace(dir string, raw []byte) {
+	t := c.tracer
+	if t == nil {
+		return
+	}
+
+	rec := TraceRecord{Time: time.Now().UTC(), Dir: dir}
+
+	if checkPacket(raw) == nil {
+		kind := PacketKind(raw[7])
+		rec.Kind = kind.String()
+
+		if kind != LoginPacket && len(raw) > minPacketSize {
+			seq := raw[8]
+			rec.Seq = &seq
+		}
+
+		if kind == LoginPacket && dir == TraceSent {
+			raw = EncodePacket(Packet{Kind: LoginPacket})
+			rec.Redacted = true
+		}
+	}
+	rec.Len = len(raw)
+	rec.Hex = hex.EncodeToString(raw)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.failed {
+		return
+	}
+
+	if err := t.enc.Encode(rec); err != nil {
+		t.failed = true
+		c.log().Warn("trace write failed, tracing stopped", "err", err)
+	}
+}
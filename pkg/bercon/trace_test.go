@@ -0,0 +1,86 @@
+package bercon_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+	"github.com/woozymasta/bercon-cli/pkg/bercon/bercontest"
+)
+
+func TestTraceReplay(t *testing.T) {
+	// record a session with a multipart response and a pushed message
+	var trace bytes.Buffer
+	long := strings.Repeat("abcdefghij", 30)
+
+	srv, c := bercontest.Open(t, "secret", bercon.WithTrace(&trace))
+	srv.SetPageSize(100)
+	srv.SetReorder(true)
+	srv.Respond("long", long)
+	srv.Handle("say", func(string) string {
+		srv.Push("(Global) Admin: restart soon")
+		return ""
+	})
+
+	if _, err := c.Send("long"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if _, err := c.Send("say -1 restart soon"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	<-c.Messages
+	if !srv.WaitAcked(2 * time.Second) {
+		t.Fatal("message not acknowledged")
+	}
+	_ = c.Close()
+
+	if strings.Contains(trace.String(), "736563726574") { // "secret"
+		t.Fatal("password leaked into trace")
+	}
+
+	records, err := bercon.ReadTrace(&trace)
+	if err != nil {
+		t.Fatalf("read trace: %v", err)
+	}
+
+	// replay it against a server with different handlers and password
+	replay := bercontest.NewServer("other")
+	defer replay.Close()
+	replay.Respond("long", "not recorded")
+	if err := replay.Replay(records); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	rc, err := bercon.Open(replay.Addr, "anything")
+	if err != nil {
+		t.Fatalf("open replay: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := rc.Send("long")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if string(data) != long {
+		t.Fatalf("got %q, want recorded response", data)
+	}
+
+	if _, err := rc.Send("say -1 restart soon"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	select {
+	case ev := <-rc.Messages:
+		if string(ev.Data) != "(Global) Admin: restart soon" {
+			t.Fatalf("got message %q", ev.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("recorded message not replayed")
+	}
+
+	if !replay.WaitAcked(2 * time.Second) {
+		t.Fatal("replayed message not acknowledged")
+	}
+}
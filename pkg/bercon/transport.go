@@ -0,0 +1,71 @@
+package bercon
+
+import (
+	"context"
+	"net"
+)
+
+// readBuffer is the socket receive buffer requested for the default UDP
+// transport, room for a burst of responses to all 256 sequence numbers
+// while readerLoop catches up. The kernel may cap it lower.
+const readBuffer = 1 << 20
+
+// DialFunc opens a transport to a BattlEye server at addr. Each Read and
+// Write on the returned conn must carry exactly one datagram, as with a
+// connected UDP socket.
+type DialFunc func(ctx context.Context, addr string) (net.Conn, error)
+
+// dialUDP resolves addr and opens a connected UDP socket to it, bound to
+// the local address set by WithLocalAddr if any, with a receive buffer of
+// readBuffer.
+func (c *Connection) dialUDP(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	if c.localAddr != "" {
+		local, err := net.ResolveUDPAddr("udp", c.localAddr)
+		if err != nil {
+			return nil, err
+		}
+		d.LocalAddr = local
+	}
+
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if uc, ok := conn.(*net.UDPConn); ok {
+		_ = uc.SetReadBuffer(readBuffer)
+	}
+
+	return conn, nil
+}
+
+// packetConn adapts an unconnected net.PacketConn to net.Conn bound to a
+// single remote address.
+type packetConn struct {
+	net.PacketConn
+	raddr net.Addr
+}
+
+// Read returns the next datagram received from the remote address.
+func (p *packetConn) Read(b []byte) (int, error) {
+	for {
+		n, addr, err := p.ReadFrom(b)
+		if err != nil {
+			return n, err
+		}
+
+		if addr != nil && addr.String() == p.raddr.String() {
+			return n, nil
+		}
+	}
+}
+
+// Write sends b as a datagram to the remote address.
+func (p *packetConn) Write(b []byte) (int, error) {
+	return p.WriteTo(b, p.raddr)
+}
+
+// RemoteAddr returns the remote address.
+func (p *packetConn) RemoteAddr() net.Addr {
+	return p.raddr
+}
@@ -0,0 +1,98 @@
+package bercon
+
+import (
+	"net"
+	"testing"
+)
+
+// pipeServer answers a login and echoes commands over one end of a pipe.
+func pipeServer(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+
+			req, err := DecodePacket(buf[:n])
+			if err != nil {
+				continue
+			}
+
+			var resp Packet
+			switch req.Kind {
+			case LoginPacket:
+				resp = Packet{Kind: LoginPacket, Data: []byte{loginSuccess}}
+			case CommandPacket:
+				resp = Packet{Kind: CommandPacket, Seq: req.Seq, Data: append([]byte("echo "), req.Data...)}
+			default:
+				continue
+			}
+
+			raw := EncodePacket(resp)
+			if _, err := conn.Write(raw); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func TestOpenWithConn(t *testing.T) {
+	client, server := net.Pipe()
+	pipeServer(t, server)
+	defer server.Close()
+
+	c, err := OpenWithConn(client, "secret")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer c.Close()
+
+	data, err := c.Send("players")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if string(data) != "echo players" {
+		t.Fatalf("got %q, want %q", data, "echo players")
+	}
+}
+
+func TestPacketConnIgnoresOtherPeers(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer pc.Close()
+
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	stranger, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stranger.Close()
+
+	conn := &packetConn{PacketConn: pc, raddr: server.LocalAddr()}
+	if _, err := stranger.WriteTo([]byte("spoofed"), pc.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.WriteTo([]byte("server"), pc.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "server" {
+		t.Fatalf("got %q, want %q", buf[:n], "server")
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Package berelay carries BattlEye RCON over mutually authenticated TLS.
+
+BattlEye RCON is plaintext UDP and is best kept on 127.0.0.1 of the game
+host. An Agent runs on the game host, accepts TLS connections from
+clients with a certificate signed by a trusted CA and forwards their
+datagrams to the local RCON port, one UDP socket per client. Dialer is
+the matching bercon transport:
+
+	cfg, err := berelay.LoadClientConfig("admin.crt", "admin.key", "ca.crt")
+	conn, err := bercon.OpenWithOptions("game-host:2310", password,
+		bercon.WithDialer(berelay.Dialer(cfg)))
+
+On the TCP stream every datagram is sent as a frame with a two byte
+big-endian length. The RCON login and all commands still pass through
+unchanged, so the RCON password is checked by the game server as usual.
+*/
+package berelay
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// IdleTimeout closes a relay session without datagrams from the
+	// client. bercon keepalives keep an open session alive.
+	IdleTimeout = 90 * time.Second
+
+	// handshakeTimeout bounds the TLS handshake of a new client.
+	handshakeTimeout = 10 * time.Second
+)
+
+var (
+	// ErrFrameTooLarge is returned when writing a datagram over MaxDatagram.
+	ErrFrameTooLarge = errors.New("berelay: datagram too large")
+
+	// ErrNoCertificates is returned for a CA file without PEM certificates.
+	ErrNoCertificates = errors.New("berelay: no certificates found")
+
+	// ErrNoClientCA is returned by Serve when the TLS config has no client
+	// CAs to verify client certificates against.
+	ErrNoClientCA = errors.New("berelay: client CA required")
+)
+
+// Agent relays TLS clients to a local RCON UDP port.
+type Agent struct {
+	cfg     *tls.Config
+	logger  *slog.Logger
+	target  string
+	allowed []string
+}
+
+// NewAgent returns an agent forwarding to the RCON address target,
+// usually 127.0.0.1 and the RConPort of the server. Client certificates
+// are always required and verified against cfg.ClientCAs.
+func NewAgent(target string, cfg *tls.Config) *Agent {
+	cfg = cfg.Clone()
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return &Agent{
+		cfg:    cfg,
+		target: target,
+		logger: slog.New(slog.DiscardHandler),
+	}
+}
+
+// SetLogger sets the logger for sessions and rejected clients. nil
+// disables logging.
+func (a *Agent) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	a.logger = l
+}
+
+// SetAllowed limits clients to certificates with one of names as common
+// name or DNS name, compared case-insensitively. Empty allows every
+// certificate signed by the client CA.
+func (a *Agent) SetAllowed(names []string) {
+	a.allowed = names
+}
+
+// Serve accepts clients on the TCP listener ln until ctx is done or ln
+// fails. ln is closed on return and open sessions are ended.
+func (a *Agent) Serve(ctx context.Context, ln net.Listener) error {
+	if a.cfg.ClientCAs == nil {
+		_ = ln.Close()
+		return ErrNoClientCA
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	stop := context.AfterFunc(ctx, func() { _ = ln.Close() })
+	defer stop()
+
+	tln := tls.NewListener(ln, a.cfg)
+	for {
+		c, err := tln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			_ = ln.Close()
+			return err
+		}
+
+		wg.Go(func() { a.session(ctx, c.(*tls.Conn)) })
+	}
+}
+
+// session authenticates one client and relays its datagrams.
+func (a *Agent) session(ctx context.Context, c *tls.Conn) {
+	defer func() { _ = c.Close() }()
+	remote := c.RemoteAddr().String()
+
+	hsCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	err := c.HandshakeContext(hsCtx)
+	cancel()
+	if err != nil {
+		a.logger.Warn("handshake failed", "remote", remote, "error", err)
+		return
+	}
+
+	cert := c.ConnectionState().PeerCertificates[0]
+	name := cert.Subject.CommonName
+	if !a.allow(cert) {
+		a.logger.Warn("client not allowed", "client", name, "remote", remote)
+		return
+	}
+
+	var d net.Dialer
+	udp, err := d.DialContext(ctx, "udp", a.target)
+	if err != nil {
+		a.logger.Error("dial rcon", "target", a.target, "error", err)
+		return
+	}
+	defer func() { _ = udp.Close() }()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.Close()
+		_ = udp.Close()
+	})
+	defer stop()
+
+	a.logger.Info("session started", "client", name, "remote", remote)
+	start := time.Now()
+
+	// the client reads nothing more once the RCON socket fails, so end
+	// the session instead of waiting for IdleTimeout
+	done := make(chan struct{})
+	go func() {
+		defer func() {
+			close(done)
+			_ = c.Close()
+		}()
+		buf := make([]byte, MaxDatagram)
+		for {
+			n, err := udp.Read(buf)
+			if err != nil {
+				return
+			}
+			if err := writeFrame(c, buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		_ = c.SetReadDeadline(time.Now().Add(IdleTimeout))
+		b, err := readFrame(c)
+		if err != nil {
+			break
+		}
+		if _, err := udp.Write(b); err != nil {
+			break
+		}
+	}
+
+	_ = udp.Close()
+	<-done
+
+	a.logger.Info("session ended", "client", name, "remote", remote,
+		"duration", time.Since(start).Round(time.Second).String())
+}
+
+// allow reports whether cert names an allowed client.
+func (a *Agent) allow(cert *x509.Certificate) bool {
+	if len(a.allowed) == 0 {
+		return true
+	}
+
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	return slices.ContainsFunc(a.allowed, func(allowed string) bool {
+		return slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, allowed) })
+	})
+}
@@ -0,0 +1,177 @@
+package berelay
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// MaxDatagram is the largest datagram a frame can carry.
+const MaxDatagram = 0xFFFF
+
+// frameHeaderSize is the big-endian datagram length before each frame.
+const frameHeaderSize = 2
+
+// writeFrame writes b as one length-prefixed frame in a single Write.
+func writeFrame(w io.Writer, b []byte) error {
+	if len(b) > MaxDatagram {
+		return ErrFrameTooLarge
+	}
+
+	frame := make([]byte, frameHeaderSize+len(b))
+	binary.BigEndian.PutUint16(frame, uint16(len(b))) // #nosec G115 -- checked against MaxDatagram
+	copy(frame[frameHeaderSize:], b)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads the next frame from r.
+func readFrame(r io.Reader) ([]byte, error) {
+	var hdr [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, binary.BigEndian.Uint16(hdr[:]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Conn carries datagrams over a stream connection, one frame per Read
+// and Write, as bercon expects from a transport.
+//
+// Frames are read by a background goroutine, so read deadlines set by
+// bercon interrupt Read without cutting a frame in half.
+type Conn struct {
+	net.Conn
+
+	frames   chan []byte
+	done     chan struct{}
+	err      error         // read error, set before frames is closed
+	deadline time.Time     // read deadline
+	changed  chan struct{} // closed when the read deadline changes
+	once     sync.Once
+	wmu      sync.Mutex
+	mu       sync.Mutex
+}
+
+// NewConn returns a datagram transport over the stream c. The Conn takes
+// ownership of c.
+func NewConn(c net.Conn) *Conn {
+	fc := &Conn{
+		Conn:    c,
+		frames:  make(chan []byte),
+		done:    make(chan struct{}),
+		changed: make(chan struct{}),
+	}
+	go fc.readLoop()
+
+	return fc
+}
+
+// readLoop feeds frames to Read until the stream fails or Close.
+func (c *Conn) readLoop() {
+	defer close(c.frames)
+
+	for {
+		b, err := readFrame(c.Conn)
+		if err != nil {
+			c.err = err
+			return
+		}
+
+		select {
+		case c.frames <- b:
+		case <-c.done:
+			c.err = net.ErrClosed
+			return
+		}
+	}
+}
+
+// Read returns the next datagram. As with UDP, a datagram longer than b
+// is truncated.
+func (c *Conn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		deadline, changed := c.deadline, c.changed
+		c.mu.Unlock()
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+			timer = time.NewTimer(d)
+			expired = timer.C
+		}
+
+		select {
+		case frame, ok := <-c.frames:
+			stopTimer(timer)
+			if !ok {
+				return 0, c.err
+			}
+			return copy(b, frame), nil
+
+		case <-expired:
+			return 0, os.ErrDeadlineExceeded
+
+		case <-changed:
+			stopTimer(timer)
+		}
+	}
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// Write sends b as one datagram.
+func (c *Conn) Write(b []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if err := writeFrame(c.Conn, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the underlying stream.
+func (c *Conn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.Conn.Close()
+}
+
+// SetDeadline sets the read and write deadlines.
+func (c *Conn) SetDeadline(t time.Time) error {
+	_ = c.SetReadDeadline(t)
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for Read. A pending Read picks up
+// the new deadline immediately.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	close(c.changed)
+	c.changed = make(chan struct{})
+	c.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package berelay
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+	"github.com/woozymasta/bercon-cli/pkg/bercon/bercontest"
+)
+
+// testCA issues certificates into a temporary directory.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+	path string // CA certificate PEM
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	ca.path = filepath.Join(ca.dir, name+".crt")
+	writePEM(t, ca.path, "CERTIFICATE", der)
+
+	return ca
+}
+
+// issue writes a leaf certificate for name and returns its cert and key
+// paths. Server certificates are valid for 127.0.0.1.
+func (ca *testCA) issue(t *testing.T, name string, server bool) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(ca.dir, name+".crt")
+	keyFile = filepath.Join(ca.dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startAgent runs an agent for the RCON address target and returns its
+// address.
+func startAgent(t *testing.T, target string, ca *testCA, allowed ...string) string {
+	t.Helper()
+
+	certFile, keyFile := ca.issue(t, "agent", true)
+	cfg, err := LoadServerConfig(certFile, keyFile, ca.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAgent(target, cfg)
+	a.SetAllowed(allowed)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	return ln.Addr().String()
+}
+
+func dialRelay(addr string, cfg *tls.Config) (*bercon.Connection, error) {
+	return bercon.OpenWithOptions(addr, "pw",
+		bercon.WithDialer(Dialer(cfg)),
+		bercon.WithDeadline(time.Second))
+}
+
+func TestRelay(t *testing.T) {
+	srv := bercontest.NewServer("pw")
+	defer func() { _ = srv.Close() }()
+
+	ca := newTestCA(t, "ca")
+	addr := startAgent(t, srv.Addr, ca)
+
+	certFile, keyFile := ca.issue(t, "admin", false)
+	cfg, err := LoadClientConfig(certFile, keyFile, ca.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := dialRelay(addr, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	resp, err := conn.Send("players")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) == 0 {
+		t.Error("empty players response")
+	}
+
+	srv.Push("(Global) Survivor: hi")
+	select {
+	case ev := <-conn.Messages:
+		if string(ev.Data) != "(Global) Survivor: hi" {
+			t.Errorf("message = %q", ev.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message over the relay")
+	}
+	if !srv.WaitAcked(time.Second) {
+		t.Error("message ack not relayed")
+	}
+
+	second, err := dialRelay(addr, cfg)
+	if err != nil {
+		t.Fatalf("second client: %v", err)
+	}
+	_ = second.Close()
+	if ok, _ := srv.Logins(); ok != 2 {
+		t.Errorf("logins = %d, want 2", ok)
+	}
+}
+
+func TestRelayRejectsClients(t *testing.T) {
+	srv := bercontest.NewServer("pw")
+	defer func() { _ = srv.Close() }()
+
+	ca := newTestCA(t, "ca")
+	addr := startAgent(t, srv.Addr, ca, "ops")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	other := newTestCA(t, "other")
+	foreign, err := tls.LoadX509KeyPair(other.issue(t, "ops", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := tls.LoadX509KeyPair(ca.issue(t, "admin", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := tls.LoadX509KeyPair(ca.issue(t, "ops", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, certs := range map[string][]tls.Certificate{
+		"no certificate":   nil,
+		"foreign CA":       {foreign},
+		"not allowed name": {admin},
+	} {
+		cfg := &tls.Config{RootCAs: roots, Certificates: certs, MinVersion: tls.VersionTLS13}
+		if conn, err := dialRelay(addr, cfg); err == nil {
+			_ = conn.Close()
+			t.Errorf("%s: login succeeded", name)
+		}
+	}
+
+	cfg := &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{ops}, MinVersion: tls.VersionTLS13}
+	conn, err := dialRelay(addr, cfg)
+	if err != nil {
+		t.Fatalf("allowed client: %v", err)
+	}
+	_ = conn.Close()
+
+	if ok, _ := srv.Logins(); ok != 1 {
+		t.Errorf("logins = %d, want 1", ok)
+	}
+}
+
+func TestRelayTargetDown(t *testing.T) {
+	// a port nobody listens on answers with ICMP port unreachable
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := pc.LocalAddr().String()
+	_ = pc.Close()
+
+	ca := newTestCA(t, "ca")
+	addr := startAgent(t, target, ca)
+
+	certFile, keyFile := ca.issue(t, "admin", false)
+	cfg, err := LoadClientConfig(certFile, keyFile, ca.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConn(tc)
+	defer func() { _ = c.Close() }()
+
+	if _, err := c.Write(bercon.EncodePacket(bercon.Packet{Kind: bercon.LoginPacket, Data: []byte("pw")})); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, MaxDatagram)
+	if _, err := c.Read(buf); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read err = %v, want the session closed", err)
+	}
+}
+
+func TestConnReadDeadline(t *testing.T) {
+	a, b := net.Pipe()
+	c := NewConn(a)
+	defer func() { _ = c.Close() }()
+
+	_ = c.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	buf := make([]byte, 8)
+	if _, err := c.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read err = %v, want deadline exceeded", err)
+	}
+
+	_ = c.SetReadDeadline(time.Time{})
+	go func() {
+		_ = writeFrame(b, []byte("first datagram"))
+		_ = writeFrame(b, []byte("second"))
+	}()
+
+	n, err := c.Read(buf)
+	if err != nil || string(buf[:n]) != "first da" {
+		t.Errorf("truncated Read = %q, %v", buf[:n], err)
+	}
+	n, err = c.Read(buf)
+	if err != nil || string(buf[:n]) != "second" {
+		t.Errorf("Read = %q, %v", buf[:n], err)
+	}
+
+	if _, err := c.Write(make([]byte, MaxDatagram+1)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("oversized Write err = %v", err)
+	}
+
+	_ = b.Close()
+	if _, err := c.Read(buf); err == nil {
+		t.Error("Read after peer close succeeded")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	certFile, keyFile := ca.issue(t, "agent", true)
+
+	if _, err := LoadServerConfig(certFile, keyFile, keyFile); !errors.Is(err, ErrNoCertificates) {
+		t.Errorf("key as CA: err = %v, want ErrNoCertificates", err)
+	}
+	if _, err := LoadClientConfig(certFile, keyFile, filepath.Join(ca.dir, "missing.crt")); err == nil {
+		t.Error("missing CA file accepted")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewAgent("127.0.0.1:2305", &tls.Config{MinVersion: tls.VersionTLS13}).Serve(context.Background(), ln); !errors.Is(err, ErrNoClientCA) {
+		t.Errorf("Serve without client CA: err = %v", err)
+	}
+}
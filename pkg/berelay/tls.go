@@ -0,0 +1,88 @@
+package berelay
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/woozymasta/bercon-cli/pkg/bercon"
+)
+
+// LoadServerConfig returns the agent TLS config: the agent certificate
+// from certFile and keyFile, and caFile with the CA certificates that
+// client certificates must chain to.
+func LoadServerConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := loadPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	}, nil
+}
+
+// LoadClientConfig returns the client TLS config: the client certificate
+// from certFile and keyFile, and caFile with the CA certificates that the
+// agent certificate must chain to. The agent certificate must be valid
+// for the host name or IP address used to dial it.
+func LoadClientConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := loadPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS13,
+	}, nil
+}
+
+// loadPool reads PEM CA certificates from path.
+func loadPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path) // #nosec G304 -- path from configuration
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: %s", ErrNoCertificates, path)
+	}
+
+	return pool, nil
+}
+
+// Dialer returns a bercon transport that connects to an agent at the
+// dialed address over TLS with cfg. Use it with bercon.WithDialer, the
+// address passed to bercon is then the agent address:
+//
+//	cfg, err := berelay.LoadClientConfig("admin.crt", "admin.key", "ca.crt")
+//	conn, err := bercon.OpenWithOptions("game-host:2310", password,
+//		bercon.WithDialer(berelay.Dialer(cfg)))
+func Dialer(cfg *tls.Config) bercon.DialFunc {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		d := tls.Dialer{Config: cfg}
+		c, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewConn(c), nil
+	}
+}